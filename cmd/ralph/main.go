@@ -1,9 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/kento/ralph/internal/commands"
 	"github.com/kento/ralph/internal/ui/format"
@@ -39,22 +41,36 @@ func main() {
 		err = commands.Init()
 	case "run":
 		maxIterations := commands.DefaultMaxIterations
-		if len(cmdArgs) > 0 {
-			if n, parseErr := strconv.Atoi(cmdArgs[0]); parseErr == nil {
+		dryRun := false
+		for _, arg := range cmdArgs {
+			if arg == "--dry-run" {
+				dryRun = true
+			} else if n, parseErr := strconv.Atoi(arg); parseErr == nil {
 				maxIterations = n
 			}
 		}
-		err = commands.Run(maxIterations)
+		if dryRun {
+			err = commands.DryRun(maxIterations)
+		} else {
+			err = commands.Run(maxIterations)
+		}
+	case "prompt":
+		err = commands.PromptPreview(flagValue(cmdArgs, "--story"))
 	case "status":
 		err = commands.Status()
 	case "prd":
 		err = commands.Prd()
+	case "progress":
+		err = commands.Progress(cmdArgs)
 	case "list":
 		err = commands.List()
 	case "logs":
-		err = commands.Logs()
+		err = commands.LogsCommand(cmdArgs)
 	case "archive":
-		err = commands.Archive()
+		err = commands.ArchiveCommand(cmdArgs)
+	case "gc":
+		dryRun := len(cmdArgs) > 0 && cmdArgs[0] == "--dry-run"
+		err = commands.GC(dryRun)
 	case "clean":
 		all := len(cmdArgs) > 0 && (cmdArgs[0] == "--all" || cmdArgs[0] == "-a")
 		err = commands.Clean(all)
@@ -67,6 +83,23 @@ func main() {
 
 	if err != nil {
 		fmt.Fprintln(os.Stderr, format.FormatError(err.Error()))
+		var interrupted *commands.InterruptedError
+		if errors.As(err, &interrupted) {
+			os.Exit(interrupted.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
+
+// flagValue returns the value of a "--name value" or "--name=value" flag
+func flagValue(args []string, name string) string {
+	for i, arg := range args {
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, name+"=") {
+			return strings.TrimPrefix(arg, name+"=")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,405 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kento/ralph/internal/prd"
+	"github.com/kento/ralph/internal/runstate"
+)
+
+// Files are the run files saved in an archive
+var Files = []string{"prd.json", "progress.txt", "prd.md"}
+
+// MetaFile is the metadata file written into each archive
+const MetaFile = "meta.json"
+
+// Meta describes an archived run
+type Meta struct {
+	ID          string             `json:"id"`
+	Branch      string             `json:"branch"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	StartedAt   time.Time          `json:"startedAt,omitzero"`
+	Completed   int                `json:"completed"`
+	Total       int                `json:"total"`
+	Blocked     int                `json:"blocked,omitempty"`
+	CostUSD     float64            `json:"costUsd"`
+	Iterations  int                `json:"iterations"`
+	Logs        []string           `json:"logs,omitempty"`    // Run logs, relative to the archive
+	Backups     []string           `json:"backups,omitempty"` // progress.txt before each compaction, relative to the archive
+	StartCommit string             `json:"startCommit,omitempty"`
+	EndCommit   string             `json:"endCommit,omitempty"`
+	Commits     []Commit           `json:"commits,omitempty"`
+	Sessions    []runstate.Session `json:"sessions,omitempty"` // Agent session and model of each iteration
+	DiffStat    string             `json:"diffStat,omitempty"`
+	Files       []string           `json:"files"`
+	Compressed  bool               `json:"compressed,omitempty"` // Stored as <id>.tar.gz
+}
+
+// Commit is a commit made on the branch during the run
+type Commit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+}
+
+// Entry is an archive on disk
+type Entry struct {
+	Meta
+	Path string `json:"-"`
+}
+
+// Date returns the day the archive was created
+func (e Entry) Date() string {
+	return e.CreatedAt.Format("2006-01-02")
+}
+
+// Dir returns the archive directory of a project
+func Dir(projectDir string) string {
+	return filepath.Join(projectDir, "archive")
+}
+
+// Get returns a single archive by ID
+func Get(projectDir, id string) (*Entry, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid archive name %q", id)
+	}
+
+	path, ok := locate(projectDir, id)
+	if !ok {
+		return nil, fmt.Errorf("archive %q not found", id)
+	}
+
+	return load(path, id), nil
+}
+
+// locate returns the path of an archive, either its directory or its
+// compressed tarball
+func locate(projectDir, id string) (string, bool) {
+	path := filepath.Join(Dir(projectDir), id)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path, true
+	}
+	if info, err := os.Stat(path + TarSuffix); err == nil && !info.IsDir() {
+		return path + TarSuffix, true
+	}
+	return "", false
+}
+
+// ReadFile returns the contents of a file stored in the archive
+func (e *Entry) ReadFile(name string) ([]byte, error) {
+	if e.Compressed {
+		return readTarFile(e.Path, name)
+	}
+	return os.ReadFile(filepath.Join(e.Path, name))
+}
+
+// PRD returns the archived prd.json
+func (e *Entry) PRD() (*prd.PRD, error) {
+	data, err := e.ReadFile("prd.json")
+	if err != nil {
+		return nil, err
+	}
+	var p prd.PRD
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// load reads an archive's meta.json, deriving the metadata from its
+// contents for archives created before meta.json existed
+func load(path, id string) *Entry {
+	entry := &Entry{Path: path}
+	compressed := strings.HasSuffix(path, TarSuffix)
+	entry.Compressed = compressed
+
+	if data, err := entry.ReadFile(MetaFile); err == nil {
+		if err := json.Unmarshal(data, &entry.Meta); err == nil {
+			entry.ID = id
+			entry.Compressed = compressed
+			return entry
+		}
+	}
+
+	entry.ID = id
+	// Legacy names start with the archive date: 2026-01-11-<branch>
+	if len(id) >= 10 {
+		if t, err := time.ParseInLocation("2006-01-02", id[:10], time.Local); err == nil {
+			entry.CreatedAt = t
+		}
+	}
+	if entry.CreatedAt.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			entry.CreatedAt = info.ModTime()
+		}
+	}
+
+	for _, file := range Files {
+		if _, err := entry.ReadFile(file); err == nil {
+			entry.Files = append(entry.Files, file)
+		}
+	}
+
+	if p, err := entry.PRD(); err == nil {
+		entry.Branch = p.BranchName
+		entry.Completed = p.CompletedCount()
+		entry.Total = p.TotalCount()
+		entry.Blocked = p.BlockedCount()
+	}
+
+	return entry
+}
+
+// newID returns an archive ID for branch that is not used yet:
+// 2026-01-11-feature, then 2026-01-11-feature-2, ...
+func newID(projectDir, branch string, now time.Time) string {
+	name := strings.TrimPrefix(branch, "ralph/")
+	name = strings.ReplaceAll(name, "/", "-")
+	if name == "" {
+		name = "run"
+	}
+	base := fmt.Sprintf("%s-%s", now.Format("2006-01-02"), name)
+
+	id := base
+	for n := 2; exists(projectDir, id); n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id
+}
+
+// exists reports whether an archive ID is taken
+func exists(projectDir, id string) bool {
+	_, err := os.Stat(filepath.Join(Dir(projectDir), id))
+	_, tarErr := os.Stat(filepath.Join(Dir(projectDir), id+TarSuffix))
+	return err == nil || tarErr == nil
+}
+
+// Create copies the active run files into a new archive with a meta.json
+// and resets the project: progress.txt gets a fresh header and the PRD
+// files and run state are removed. With compress the archive is stored as
+// <id>.tar.gz instead of a directory.
+func Create(projectDir, workingDir string, compress bool) (*Entry, error) {
+	p, err := prd.Load(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := runstate.Load(projectDir)
+	if err != nil {
+		state = &runstate.State{}
+	}
+	if state.Branch != "" && state.Branch != p.BranchName {
+		// Left over from another branch, it doesn't describe this run
+		state = &runstate.State{}
+	}
+
+	now := time.Now()
+	id := newID(projectDir, p.BranchName, now)
+	archiveDir := filepath.Join(Dir(projectDir), id)
+
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Copy files to archive
+	if err := copyFiles(projectDir, archiveDir); err != nil {
+		return nil, err
+	}
+
+	// Move the run's logs along so the archive documents the whole feature
+	logs, err := moveLogs(projectDir, archiveDir, p.BranchName, state.Logs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive logs: %w", err)
+	}
+
+	// and the full progress history saved before compactions
+	backups, err := moveBackups(projectDir, archiveDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive progress backups: %w", err)
+	}
+
+	entry := &Entry{Path: archiveDir, Meta: Meta{
+		ID:          id,
+		Branch:      p.BranchName,
+		CreatedAt:   now,
+		StartedAt:   state.StartedAt,
+		Completed:   p.CompletedCount(),
+		Total:       p.TotalCount(),
+		Blocked:     p.BlockedCount(),
+		CostUSD:     state.CostUSD,
+		Iterations:  state.Iterations,
+		Sessions:    state.Sessions,
+		Logs:        logs,
+		Backups:     backups,
+		StartCommit: state.StartCommit,
+	}}
+	recordHistory(&entry.Meta, workingDir)
+	for _, file := range Files {
+		if _, err := os.Stat(filepath.Join(archiveDir, file)); err == nil {
+			entry.Files = append(entry.Files, file)
+		}
+	}
+
+	if err := writeMeta(entry); err != nil {
+		return nil, err
+	}
+
+	if compress {
+		if entry.Path, err = compressDir(archiveDir); err != nil {
+			return nil, fmt.Errorf("failed to compress archive: %w", err)
+		}
+		entry.Compressed = true
+	}
+
+	// Reset progress.txt
+	header := fmt.Sprintf("# Progress Log\n# Project: %s\n# Reset: %s\n\n", workingDir, now.Format("2006-01-02 15:04:05"))
+	progressPath := filepath.Join(projectDir, "progress.txt")
+	if err := os.WriteFile(progressPath, []byte(header), 0644); err != nil {
+		return nil, err
+	}
+
+	// Remove PRD files and the finished run's state
+	os.Remove(filepath.Join(projectDir, "prd.json"))
+	os.Remove(filepath.Join(projectDir, "prd.md"))
+	os.Remove(filepath.Join(projectDir, ".last-branch"))
+	runstate.Remove(projectDir)
+
+	if _, err := RebuildIndex(projectDir); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// writeMeta writes an archive's meta.json
+func writeMeta(entry *Entry) error {
+	data, err := json.MarshalIndent(entry.Meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(entry.Path, MetaFile), append(data, '\n'), 0644)
+}
+
+// Restore makes an archived run the active one again. A run that is
+// currently active is archived first (returned as previous). The restored
+// archive is removed once its files are back in the project directory.
+// compress applies to the archive of the previously active run.
+func Restore(projectDir, workingDir, id string, compress bool) (restored, previous *Entry, err error) {
+	entry, err := Get(projectDir, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !contains(entry.Files, "prd.json") {
+		return nil, nil, fmt.Errorf("archive %q has no prd.json to restore", id)
+	}
+
+	// Move the archive aside so archiving the active run can't touch it.
+	// Compressed archives are unpacked there instead.
+	staged := filepath.Join(Dir(projectDir), ".restore-"+id)
+	unstage := func() { os.Rename(staged, entry.Path) }
+	if entry.Compressed {
+		if err := extractTarGz(entry.Path, staged); err != nil {
+			os.RemoveAll(staged)
+			return nil, nil, err
+		}
+		unstage = func() { os.RemoveAll(staged) }
+	} else if err := os.Rename(entry.Path, staged); err != nil {
+		return nil, nil, err
+	}
+
+	// Until the staged copy is removed, a failure puts the archive back as
+	// it was so it stays listed. An archived active run stays archived.
+	committed := false
+	defer func() {
+		if !committed {
+			moveFiles(projectDir, staged, entry.Logs)
+			moveFiles(projectDir, staged, entry.Backups)
+			unstage()
+		}
+	}()
+
+	if prd.Exists(projectDir) {
+		if previous, err = Create(projectDir, workingDir, compress); err != nil {
+			return nil, nil, fmt.Errorf("failed to archive the active run: %w", err)
+		}
+	}
+
+	// Files not present in the archive must not leak in from a previous run
+	for _, file := range Files {
+		os.Remove(filepath.Join(projectDir, file))
+	}
+
+	if err := copyFiles(staged, projectDir); err != nil {
+		return nil, previous, err
+	}
+	if err := moveFiles(staged, projectDir, entry.Logs); err != nil {
+		return nil, previous, err
+	}
+	if err := moveFiles(staged, projectDir, entry.Backups); err != nil {
+		return nil, previous, err
+	}
+
+	// Record the restored branch so the next run doesn't auto-archive it
+	if entry.Branch != "" {
+		if err := os.WriteFile(filepath.Join(projectDir, ".last-branch"), []byte(entry.Branch), 0644); err != nil {
+			return nil, previous, err
+		}
+	}
+
+	// Carry the archived run's totals over so archiving it again keeps them
+	state := &runstate.State{
+		Branch:      entry.Branch,
+		StartedAt:   entry.StartedAt,
+		Iterations:  entry.Iterations,
+		CostUSD:     entry.CostUSD,
+		StartCommit: entry.StartCommit,
+		Logs:        entry.Logs,
+		Sessions:    entry.Sessions,
+	}
+	if err := state.Save(projectDir); err != nil {
+		return nil, previous, err
+	}
+
+	committed = true
+	if err := os.RemoveAll(staged); err != nil {
+		return nil, previous, err
+	}
+	if entry.Compressed {
+		if err := os.Remove(entry.Path); err != nil {
+			return nil, previous, err
+		}
+	}
+
+	if _, err := RebuildIndex(projectDir); err != nil {
+		return nil, previous, err
+	}
+
+	return entry, previous, nil
+}
+
+// copyFiles copies the run files that exist in src into dst
+func copyFiles(src, dst string) error {
+	for _, file := range Files {
+		data, err := os.ReadFile(filepath.Join(src, file))
+		if err != nil {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dst, file), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kento/ralph/internal/progress"
+)
+
+const samplePRD = `{"project":"x","branchName":"ralph/tasks","userStories":[
+{"id":"US-001","title":"One","priority":1,"passes":true},
+{"id":"US-002","title":"Two","priority":2,"passes":false}]}`
+
+// newProject creates a project directory with an active run
+func newProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeRun(t, dir)
+	return dir
+}
+
+func writeRun(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "prd.json"), []byte(samplePRD), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "progress.txt"), []byte("# Progress Log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateUniqueIDs(t *testing.T) {
+	dir := newProject(t)
+
+	first, err := Create(dir, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRun(t, dir)
+	second, err := Create(dir, dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.ID == second.ID {
+		t.Fatalf("both archives got ID %q", first.ID)
+	}
+	if want := first.ID + "-2"; second.ID != want {
+		t.Errorf("second ID = %q, want %q", second.ID, want)
+	}
+
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("List returned %d archives, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.Completed != 1 || e.Total != 2 || e.Branch != "ralph/tasks" {
+			t.Errorf("%s: got %d/%d on %q", e.ID, e.Completed, e.Total, e.Branch)
+		}
+	}
+}
+
+func TestCreateMovesBranchLogs(t *testing.T) {
+	dir := newProject(t)
+	logsDir := filepath.Join(dir, "logs", "ralph")
+	os.MkdirAll(logsDir, 0755)
+	for _, name := range []string{"tasks_2026-01-11-10-00-00.log", "other_2026-01-11-10-00-00.log"} {
+		os.WriteFile(filepath.Join(logsDir, name), []byte("log"), 0644)
+	}
+
+	entry, err := Create(dir, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join("logs", "ralph", "tasks_2026-01-11-10-00-00.log")
+	if len(entry.Logs) != 1 || entry.Logs[0] != want {
+		t.Fatalf("Logs = %v, want [%s]", entry.Logs, want)
+	}
+	if _, err := os.Stat(filepath.Join(entry.Path, want)); err != nil {
+		t.Errorf("log not moved into archive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs", "ralph", "other_2026-01-11-10-00-00.log")); err != nil {
+		t.Errorf("log of another branch was moved: %v", err)
+	}
+
+	// Restoring puts the logs back
+	if _, _, err := Restore(dir, dir, entry.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, want)); err != nil {
+		t.Errorf("log not restored: %v", err)
+	}
+}
+
+func TestCreateMovesProgressBackups(t *testing.T) {
+	dir := newProject(t)
+	backup, err := progress.Backup(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := Create(dir, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rel, _ := filepath.Rel(dir, backup)
+	if len(entry.Backups) != 1 || entry.Backups[0] != rel {
+		t.Fatalf("Backups = %v, want [%s]", entry.Backups, rel)
+	}
+	if _, err := os.Stat(filepath.Join(entry.Path, rel)); err != nil {
+		t.Errorf("backup not moved into archive: %v", err)
+	}
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Errorf("backup left in the project: %v", err)
+	}
+
+	// Restoring puts the backups back
+	if _, _, err := Restore(dir, dir, entry.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Errorf("backup not restored: %v", err)
+	}
+}
+
+func TestCreateKeepsLogsOfBranchesSharingAPrefix(t *testing.T) {
+	dir := newProject(t)
+	logsDir := filepath.Join(dir, "logs", "ralph")
+	os.MkdirAll(logsDir, 0755)
+	names := []string{
+		"tasks_2026-01-11-10-00-00.jsonl",
+		"tasks_bulk_2026-01-11-11-00-00.log",
+		"tasks_bulk_2026-01-11-11-00-00.jsonl",
+	}
+	for _, name := range names {
+		os.WriteFile(filepath.Join(logsDir, name), []byte("log"), 0644)
+	}
+
+	entry, err := Create(dir, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join("logs", "ralph", "tasks_2026-01-11-10-00-00.jsonl")
+	if len(entry.Logs) != 1 || entry.Logs[0] != want {
+		t.Fatalf("Logs = %v, want [%s]", entry.Logs, want)
+	}
+	for _, name := range names[1:] {
+		if _, err := os.Stat(filepath.Join(logsDir, name)); err != nil {
+			t.Errorf("log of ralph/tasks_bulk was moved: %v", err)
+		}
+	}
+}
+
+func TestRestoreFailureKeepsArchive(t *testing.T) {
+	log := filepath.Join("logs", "ralph", "tasks_2026-01-11-10-00-00.log")
+	// A non-empty directory in place of a file makes writing it fail:
+	// progress.txt fails copyFiles, .last-branch fails after the logs moved
+	for _, blocker := range []string{"progress.txt", ".last-branch"} {
+		for _, compress := range []bool{false, true} {
+			dir := newProject(t)
+			os.MkdirAll(filepath.Join(dir, "logs", "ralph"), 0755)
+			os.WriteFile(filepath.Join(dir, log), []byte("log"), 0644)
+			entry, err := Create(dir, dir, compress)
+			if err != nil {
+				t.Fatal(err)
+			}
+			os.Remove(filepath.Join(dir, blocker))
+			os.MkdirAll(filepath.Join(dir, blocker, "x"), 0755)
+
+			if _, _, err := Restore(dir, dir, entry.ID, compress); err == nil {
+				t.Fatalf("%s, compress %v: Restore succeeded", blocker, compress)
+			}
+			entries, err := List(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].ID != entry.ID {
+				t.Fatalf("%s, compress %v: List = %v, want the archive still listed", blocker, compress, entries)
+			}
+			if !compress {
+				if _, err := os.Stat(filepath.Join(entry.Path, log)); err != nil {
+					t.Errorf("%s: log not back in the archive: %v", blocker, err)
+				}
+			}
+
+			// Once the problem is fixed the archive restores normally
+			os.RemoveAll(filepath.Join(dir, blocker))
+			if _, _, err := Restore(dir, dir, entry.ID, compress); err != nil {
+				t.Fatalf("%s, compress %v: retry failed: %v", blocker, compress, err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, log)); err != nil {
+				t.Errorf("%s, compress %v: log not restored: %v", blocker, compress, err)
+			}
+		}
+	}
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	dir := newProject(t)
+
+	entry, err := Create(dir, dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Compressed || filepath.Ext(entry.Path) != ".gz" {
+		t.Fatalf("archive not compressed: %s", entry.Path)
+	}
+
+	// Drop the index so it has to be rebuilt from the tarball
+	os.Remove(filepath.Join(Dir(dir), IndexFile))
+	got, err := Get(dir, entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := got.PRD()
+	if err != nil || len(p.UserStories) != 2 {
+		t.Fatalf("PRD() = %v, %v", p, err)
+	}
+
+	restored, _, err := Restore(dir, dir, entry.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Branch != "ralph/tasks" {
+		t.Errorf("restored branch = %q", restored.Branch)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "prd.json")); err != nil {
+		t.Errorf("prd.json not restored: %v", err)
+	}
+	if _, err := os.Stat(entry.Path); !os.IsNotExist(err) {
+		t.Errorf("tarball still present after restore")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	for i, id := range []string{"old", "mid", "new"} {
+		archiveDir := filepath.Join(Dir(dir), id)
+		entry := &Entry{Path: archiveDir, Meta: Meta{
+			ID:        id,
+			CreatedAt: now.Add(time.Duration(i-2) * 10 * 24 * time.Hour),
+		}}
+		if err := os.MkdirAll(archiveDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeMeta(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Logs left in the project: one stale, one fresh and one of the active run
+	os.MkdirAll(filepath.Join(dir, "logs"), 0755)
+	stale := now.Add(-20 * 24 * time.Hour)
+	for _, name := range []string{"stale", "fresh", "active"} {
+		path := filepath.Join(dir, "logs", name+".log")
+		os.WriteFile(path, []byte("log"), 0644)
+		if name != "fresh" {
+			os.Chtimes(path, stale, stale)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "run-state.json"), []byte(`{"logs":["logs/active.log"]}`), 0644)
+
+	// Progress backups left in the project expire like logs
+	os.MkdirAll(filepath.Join(dir, progress.BackupDir), 0755)
+	backup := filepath.Join(dir, progress.BackupDir, "progress-old.txt")
+	os.WriteFile(backup, []byte("# Progress Log\n"), 0644)
+	os.Chtimes(backup, stale, stale)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []string
+	}{
+		{"unset", Policy{}, nil},
+		{"keep newest", Policy{Keep: 1}, []string{"mid", "old"}},
+		{"max age", Policy{MaxAge: 15 * 24 * time.Hour}, []string{"old", "logs/stale.log", "backups/progress-old.txt"}},
+		{"either rule keeps", Policy{Keep: 2, MaxAge: 5 * 24 * time.Hour}, []string{"old", "logs/stale.log", "backups/progress-old.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removals, err := Collect(dir, tt.policy, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]bool{}
+			for _, r := range removals {
+				if r.Archive != "" {
+					got[r.Archive] = true
+				} else {
+					rel, _ := filepath.Rel(dir, r.Path)
+					got[filepath.ToSlash(rel)] = true
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("removals = %v, want %v", got, tt.want)
+			}
+			for _, w := range tt.want {
+				if !got[w] {
+					t.Errorf("missing removal %s in %v", w, got)
+				}
+			}
+		})
+	}
+}
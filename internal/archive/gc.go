@@ -0,0 +1,130 @@
+package archive
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kento/ralph/internal/progress"
+	"github.com/kento/ralph/internal/runstate"
+)
+
+// Policy decides which archives and logs are kept. An archive is kept when
+// it is one of the newest Keep archives or younger than MaxAge; a zero
+// value disables that rule.
+type Policy struct {
+	Keep   int
+	MaxAge time.Duration
+}
+
+// IsSet reports whether the policy removes anything at all
+func (p Policy) IsSet() bool {
+	return p.Keep > 0 || p.MaxAge > 0
+}
+
+// keeps reports whether the archive at position i (newest first) is kept
+func (p Policy) keeps(i int, createdAt, now time.Time) bool {
+	if !p.IsSet() {
+		return true
+	}
+	return (p.Keep > 0 && i < p.Keep) || (p.MaxAge > 0 && now.Sub(createdAt) < p.MaxAge)
+}
+
+// Removal is an archive, log or progress backup that garbage collection
+// deletes
+type Removal struct {
+	Path    string
+	Archive string // Archive ID, empty for logs and backups
+	Bytes   int64
+}
+
+// Collect returns the archives and logs of a project that the policy
+// removes. Archived logs and backups go with their archive; logs and
+// progress backups left in the project expire after MaxAge unless the
+// active run refers to them.
+func Collect(projectDir string, policy Policy, now time.Time) ([]Removal, error) {
+	if !policy.IsSet() {
+		return nil, nil
+	}
+
+	entries, err := List(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removals []Removal
+	for i, e := range entries {
+		if !policy.keeps(i, e.CreatedAt, now) {
+			removals = append(removals, Removal{Path: e.Path, Archive: e.ID, Bytes: size(e.Path)})
+		}
+	}
+
+	if policy.MaxAge == 0 {
+		return removals, nil
+	}
+
+	keptLogs := make(map[string]bool)
+	if state, err := runstate.Load(projectDir); err == nil {
+		for _, log := range state.Logs {
+			keptLogs[filepath.Clean(log)] = true
+		}
+	}
+
+	expire := func(dir string, match func(path string) bool) {
+		filepath.WalkDir(filepath.Join(projectDir, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !match(path) {
+				return nil
+			}
+			rel, err := filepath.Rel(projectDir, path)
+			if err != nil || keptLogs[rel] {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if now.Sub(info.ModTime()) >= policy.MaxAge {
+				removals = append(removals, Removal{Path: path, Bytes: info.Size()})
+			}
+			return nil
+		})
+	}
+	expire("logs", isLog)
+	expire(progress.BackupDir, func(string) bool { return true })
+
+	return removals, nil
+}
+
+// Remove deletes collected archives and logs and returns the bytes
+// reclaimed. The archive index is rebuilt afterwards.
+func Remove(projectDir string, removals []Removal) (int64, error) {
+	var reclaimed int64
+	for _, r := range removals {
+		if err := os.RemoveAll(r.Path); err != nil {
+			return reclaimed, err
+		}
+		reclaimed += r.Bytes
+	}
+
+	if _, err := RebuildIndex(projectDir); err != nil {
+		return reclaimed, err
+	}
+	return reclaimed, nil
+}
+
+// size returns the size of a file or the total size of a directory
+func size(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
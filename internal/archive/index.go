@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IndexFile is the per-project archive index
+const IndexFile = "index.json"
+
+// Index lists every archive of a project with its metadata
+type Index struct {
+	Archives []Meta `json:"archives"`
+}
+
+// indexPath returns the index.json path of a project
+func indexPath(projectDir string) string {
+	return filepath.Join(Dir(projectDir), IndexFile)
+}
+
+// List returns the archives of a project, newest first. The index is
+// rebuilt when it is missing or out of sync with the archive directory.
+func List(projectDir string) ([]Entry, error) {
+	index, err := LoadIndex(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(index.Archives))
+	for i, meta := range index.Archives {
+		path := filepath.Join(Dir(projectDir), meta.ID)
+		if meta.Compressed {
+			path += TarSuffix
+		}
+		entries[i] = Entry{Meta: meta, Path: path}
+	}
+	return entries, nil
+}
+
+// LoadIndex reads archive/index.json, rebuilding it if needed
+func LoadIndex(projectDir string) (*Index, error) {
+	ids, err := archiveIDs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var index Index
+	data, err := os.ReadFile(indexPath(projectDir))
+	if err == nil && json.Unmarshal(data, &index) == nil && inSync(&index, ids) {
+		return &index, nil
+	}
+
+	return RebuildIndex(projectDir)
+}
+
+// RebuildIndex scans the archive directory and rewrites index.json
+func RebuildIndex(projectDir string) (*Index, error) {
+	ids, err := archiveIDs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{Archives: []Meta{}}
+	for _, id := range ids {
+		if path, ok := locate(projectDir, id); ok {
+			index.Archives = append(index.Archives, load(path, id).Meta)
+		}
+	}
+
+	sort.SliceStable(index.Archives, func(i, j int) bool {
+		a, b := index.Archives[i], index.Archives[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	if len(ids) == 0 {
+		// Don't create an archive directory just for an empty index
+		if _, err := os.Stat(Dir(projectDir)); os.IsNotExist(err) {
+			return index, nil
+		}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(indexPath(projectDir), append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// archiveIDs returns the archive names of a project, from both archive
+// directories and compressed tarballs
+func archiveIDs(projectDir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(Dir(projectDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, d := range dirEntries {
+		name := d.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !d.IsDir() {
+			if !strings.HasSuffix(name, TarSuffix) {
+				continue
+			}
+			name = strings.TrimSuffix(name, TarSuffix)
+		}
+		if !seen[name] {
+			seen[name] = true
+			ids = append(ids, name)
+		}
+	}
+	return ids, nil
+}
+
+// inSync reports whether the index lists exactly the given archive IDs
+func inSync(index *Index, ids []string) bool {
+	if len(index.Archives) != len(ids) {
+		return false
+	}
+	known := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		known[id] = true
+	}
+	for _, meta := range index.Archives {
+		if !known[meta.ID] {
+			return false
+		}
+	}
+	return true
+}
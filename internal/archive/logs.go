@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kento/ralph/internal/git"
+	"github.com/kento/ralph/internal/progress"
+	"github.com/kento/ralph/internal/runlog"
+)
+
+// moveLogs moves a run's logs from the project into the archive: the logs
+// recorded in the run state plus every logs/<branch>_<date>.log and .jsonl.
+// Returns the moved paths, which are relative to both directories.
+func moveLogs(projectDir, archiveDir, branch string, stateLogs []string) ([]string, error) {
+	found := make(map[string]bool)
+	for _, log := range stateLogs {
+		found[filepath.Clean(log)] = true
+	}
+
+	if branch != "" {
+		logsDir := filepath.Join(projectDir, "logs")
+		filepath.WalkDir(logsDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isLog(path) {
+				return nil
+			}
+			if rel, err := filepath.Rel(logsDir, path); err == nil && logBranch(rel) == filepath.FromSlash(branch) {
+				found[filepath.Join("logs", rel)] = true
+			}
+			return nil
+		})
+	}
+
+	var logs []string
+	for log := range found {
+		if _, err := os.Stat(filepath.Join(projectDir, log)); err == nil {
+			logs = append(logs, log)
+		}
+	}
+	sort.Strings(logs)
+
+	if err := moveFiles(projectDir, archiveDir, logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// moveBackups moves the progress.txt backups from the project into the
+// archive and returns their paths, relative to both directories
+func moveBackups(projectDir, archiveDir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(projectDir, progress.BackupDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var backups []string
+	for _, d := range dirEntries {
+		if !d.IsDir() {
+			backups = append(backups, filepath.Join(progress.BackupDir, d.Name()))
+		}
+	}
+
+	if err := moveFiles(projectDir, archiveDir, backups); err != nil {
+		return nil, err
+	}
+	return backups, nil
+}
+
+// logBranch returns the branch of a <branch>_<date>.log or .jsonl path
+// relative to the logs directory, or "" when the name has no valid date.
+// Splitting at the last "_" keeps branches that share a prefix apart.
+func logBranch(rel string) string {
+	name := strings.TrimSuffix(strings.TrimSuffix(rel, ".log"), ".jsonl")
+	i := strings.LastIndex(name, "_")
+	if i <= 0 {
+		return ""
+	}
+	if _, err := time.Parse(runlog.DateFormat, name[i+1:]); err != nil {
+		return ""
+	}
+	return name[:i]
+}
+
+// isLog reports whether path is a run log, rendered (.log) or structured (.jsonl)
+func isLog(path string) bool {
+	return strings.HasSuffix(path, ".log") || strings.HasSuffix(path, ".jsonl")
+}
+
+// moveFiles moves the given relative paths from src to dst, skipping
+// those that don't exist
+func moveFiles(src, dst string, paths []string) error {
+	for _, rel := range paths {
+		from := filepath.Join(src, rel)
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			continue
+		}
+		to := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordHistory fills in the commits made on the run's branch. The range
+// starts at the commit recorded when the run began, or where the branch
+// forked off the default branch.
+func recordHistory(meta *Meta, workingDir string) {
+	end := ""
+	if meta.Branch != "" {
+		end, _ = git.RevParse(workingDir, meta.Branch)
+	}
+	if end == "" {
+		var err error
+		if end, err = git.Head(workingDir); err != nil {
+			return
+		}
+	}
+	meta.EndCommit = end
+
+	if meta.StartCommit == "" {
+		if base, err := git.DefaultBranch(workingDir); err == nil && base != meta.Branch {
+			meta.StartCommit, _ = git.MergeBase(workingDir, base, end)
+		}
+	}
+	if meta.StartCommit == "" || meta.StartCommit == end {
+		return
+	}
+
+	if commits, err := git.Log(workingDir, meta.StartCommit, end); err == nil {
+		for _, c := range commits {
+			meta.Commits = append(meta.Commits, Commit{Hash: c.Hash, Subject: c.Subject})
+		}
+	}
+	meta.DiffStat, _ = git.DiffStat(workingDir, meta.StartCommit, end)
+}
@@ -0,0 +1,164 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// TarSuffix is the file extension of compressed archives
+const TarSuffix = ".tar.gz"
+
+// compressDir packs an archive directory into <dir>.tar.gz and removes the
+// directory. Entries are stored relative to the archive root.
+func compressDir(dir string) (string, error) {
+	dest := dir + TarSuffix
+	if err := writeTarGz(dir, dest); err != nil {
+		os.Remove(dest)
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// writeTarGz writes the files below dir into a gzipped tarball at dest
+func writeTarGz(dir, dest string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// walkTarGz calls fn for every regular file in a gzipped tarball until fn
+// returns false
+func walkTarGz(src string, fn func(name string, r io.Reader) (bool, error)) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(src), err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(src), err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		more, err := fn(path.Clean(header.Name), tr)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// readTarFile returns the contents of a single file in a gzipped tarball
+func readTarFile(src, name string) ([]byte, error) {
+	var data []byte
+	found := false
+	err := walkTarGz(src, func(n string, r io.Reader) (bool, error) {
+		if n != name {
+			return true, nil
+		}
+		found = true
+		var err error
+		data, err = io.ReadAll(r)
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "open", Path: src + ":" + name, Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+// extractTarGz unpacks a gzipped tarball into dir
+func extractTarGz(src, dir string) error {
+	return walkTarGz(src, func(name string, r io.Reader) (bool, error) {
+		if !fs.ValidPath(name) || strings.HasPrefix(name, "../") {
+			return false, fmt.Errorf("invalid path %q in %s", name, filepath.Base(src))
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return false, err
+		}
+
+		dst, err := os.Create(target)
+		if err != nil {
+			return false, err
+		}
+		if _, err := io.Copy(dst, r); err != nil {
+			dst.Close()
+			return false, err
+		}
+		return true, dst.Close()
+	})
+}
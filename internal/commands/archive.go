@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/kento/ralph/internal/archive"
+	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/progress"
+	"github.com/kento/ralph/internal/project"
+	"github.com/kento/ralph/internal/ui/format"
+	"github.com/kento/ralph/internal/ui/styles"
+)
+
+// archiveUsage lists the archive subcommands
+const archiveUsage = "Usage: ralph archive [list|show <name>|restore <name>]"
+
+// ArchiveCommand dispatches the archive subcommands. Without arguments it
+// archives the active run.
+func ArchiveCommand(args []string) error {
+	if len(args) == 0 {
+		return Archive()
+	}
+
+	name := ""
+	if len(args) > 1 {
+		name = args[1]
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return ArchiveList()
+	case "show":
+		if name == "" {
+			return fmt.Errorf("missing archive name\n%s", archiveUsage)
+		}
+		return ArchiveShow(name)
+	case "restore":
+		if name == "" {
+			return fmt.Errorf("missing archive name\n%s", archiveUsage)
+		}
+		return ArchiveRestore(name)
+	default:
+		return fmt.Errorf("unknown archive command: %s\n%s", args[0], archiveUsage)
+	}
+}
+
+// ArchiveList lists the current project's archives
+func ArchiveList() error {
+	projectDir, err := project.GetProjectDir()
+	if err != nil {
+		return err
+	}
+
+	entries, err := archive.List(projectDir)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println(styles.Muted.Render("No archives found."))
+		return nil
+	}
+
+	fmt.Println(format.FormatHeader("Archives"))
+	fmt.Println()
+
+	nameWidth := MinNameDisplayLen
+	for _, e := range entries {
+		nameWidth = max(nameWidth, min(len(e.ID), MaxNameDisplayLen))
+	}
+
+	columns := []table.Column{
+		{Title: "Name", Width: nameWidth},
+		{Title: "Date", Width: 10},
+		{Title: "Stories", Width: 10},
+		{Title: "Iterations", Width: 10},
+		{Title: "Cost", Width: 8},
+	}
+
+	rows := []table.Row{}
+	for _, e := range entries {
+		name := e.ID
+		if len(name) > nameWidth {
+			name = name[:nameWidth-3] + "..."
+		}
+		rows = append(rows, table.Row{name, e.Date(), archiveStories(e), archiveIterations(e), archiveCost(e)})
+	}
+
+	fmt.Println(renderTable(columns, rows))
+	fmt.Println()
+	fmt.Println(format.FormatNextStep("ralph archive show <name>", "for details"))
+
+	return nil
+}
+
+// ArchiveShow prints the details of an archive
+func ArchiveShow(name string) error {
+	projectDir, err := project.GetProjectDir()
+	if err != nil {
+		return err
+	}
+
+	entry, err := archive.Get(projectDir, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(format.FormatHeader("Archive " + entry.ID))
+	fmt.Println()
+	fmt.Println(format.FormatKeyValue("Date", entry.Date()))
+	if entry.Branch != "" {
+		fmt.Println(format.FormatKeyValue("Branch", entry.Branch))
+	}
+	if !entry.StartedAt.IsZero() {
+		fmt.Println(format.FormatKeyValue("Started", entry.StartedAt.Format("2006-01-02 15:04")))
+	}
+	fmt.Println(format.FormatKeyValue("Archived", entry.CreatedAt.Format("2006-01-02 15:04")))
+	fmt.Println(format.FormatKeyValue("Stories", archiveStories(*entry)))
+	if entry.Blocked > 0 {
+		fmt.Println(format.FormatKeyValue("Blocked", fmt.Sprintf("%d", entry.Blocked)))
+	}
+	fmt.Println(format.FormatKeyValue("Iterations", archiveIterations(*entry)))
+	fmt.Println(format.FormatKeyValue("Cost", archiveCost(*entry)))
+	if models := archiveModels(*entry); models != "" {
+		fmt.Println(format.FormatKeyValue("Models", models))
+	}
+	if entry.StartCommit != "" || entry.EndCommit != "" {
+		commitRange := fmt.Sprintf("%s..%s", shortCommit(entry.StartCommit), shortCommit(entry.EndCommit))
+		if len(entry.Commits) > 0 {
+			commitRange = fmt.Sprintf("%d (%s)", len(entry.Commits), commitRange)
+		}
+		fmt.Println(format.FormatKeyValue("Commits", commitRange))
+	}
+	if entry.DiffStat != "" {
+		fmt.Println(format.FormatKeyValue("Changes", entry.DiffStat))
+	}
+	fmt.Println(format.FormatKeyValue("Files", strings.Join(entry.Files, ", ")))
+	if len(entry.Logs) > 0 {
+		fmt.Println(format.FormatKeyValue("Logs", fmt.Sprintf("%d", len(entry.Logs))))
+	}
+	if len(entry.Backups) > 0 {
+		fmt.Println(format.FormatKeyValue("Backups", fmt.Sprintf("%d (progress.txt before compaction)", len(entry.Backups))))
+	}
+	fmt.Println(format.FormatKeyValue("Location", entry.Path))
+
+	if len(entry.Commits) > 0 {
+		fmt.Println()
+		for _, c := range entry.Commits {
+			fmt.Printf("  %s %s\n", styles.Muted.Render(shortCommit(c.Hash)), c.Subject)
+		}
+	}
+	if len(entry.Logs)+len(entry.Backups) > 0 {
+		fmt.Println()
+		for _, file := range append(slices.Clone(entry.Logs), entry.Backups...) {
+			fmt.Println(format.FormatBullet(file))
+		}
+	}
+
+	if p, err := entry.PRD(); err == nil && len(p.UserStories) > 0 {
+		fmt.Println()
+		for _, story := range p.UserStories {
+			icon := styles.Muted.Render(styles.Bullet)
+			if story.Passes {
+				icon = styles.SuccessText.Render(styles.CheckIcon)
+			} else if story.Blocked {
+				icon = styles.WarningText.Render(styles.WarningIcon)
+			}
+			fmt.Printf("  %s [%s] %s\n", icon, story.ID, story.Title)
+		}
+	}
+
+	if data, err := entry.ReadFile(progress.FileName); err == nil {
+		log := progress.Parse(string(data))
+		fmt.Println()
+		fmt.Println(format.FormatKeyValue("Progress", fmt.Sprintf("%d entries, %d patterns", len(log.Entries), len(log.Patterns))))
+	}
+
+	fmt.Println()
+	fmt.Println(format.FormatNextStep("ralph archive restore "+entry.ID, "to make it the active run"))
+
+	return nil
+}
+
+// ArchiveRestore makes an archived run active again, archiving the current
+// run first if there is one
+func ArchiveRestore(name string) error {
+	projectDir, err := project.GetProjectDir()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	cwd, _ := os.Getwd()
+	restored, previous, err := archive.Restore(projectDir, cwd, name, cfg.CompressArchives())
+	if previous != nil {
+		fmt.Println(format.FormatKeyValue("Archived active run", previous.Path))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(format.FormatSuccess("Archive restored"))
+	fmt.Println()
+	fmt.Println(format.FormatKeyValue("Restored", strings.Join(restored.Files, ", ")))
+	if restored.Branch != "" {
+		fmt.Println(format.FormatKeyValue("Branch", restored.Branch))
+	}
+	fmt.Println(format.FormatKeyValue("Stories", archiveStories(*restored)))
+	fmt.Println()
+	fmt.Println(format.FormatNextStep("ralph run", "to continue"))
+
+	return nil
+}
+
+// archiveStories renders the completed/total story count of an archive
+func archiveStories(e archive.Entry) string {
+	if e.Total == 0 {
+		return "-"
+	}
+	stories := fmt.Sprintf("%d/%d", e.Completed, e.Total)
+	if e.Completed == e.Total {
+		stories += " " + styles.CheckIcon
+	}
+	return stories
+}
+
+// archiveIterations renders the iteration count of an archive
+func archiveIterations(e archive.Entry) string {
+	if e.Iterations == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", e.Iterations)
+}
+
+// archiveCost renders the agent cost of an archive
+func archiveCost(e archive.Entry) string {
+	if e.CostUSD == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("$%.2f", e.CostUSD)
+}
+
+// archiveModels lists the agent models that worked on an archive
+func archiveModels(e archive.Entry) string {
+	var models []string
+	for _, s := range e.Sessions {
+		if s.Model != "" && !slices.Contains(models, s.Model) {
+			models = append(models, s.Model)
+		}
+	}
+	return strings.Join(models, ", ")
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(hash string) string {
+	if hash == "" {
+		return "?"
+	}
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
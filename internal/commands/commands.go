@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/kento/ralph/internal/archive"
 	"github.com/kento/ralph/internal/config"
 	"github.com/kento/ralph/internal/prd"
 	"github.com/kento/ralph/internal/project"
@@ -27,7 +28,7 @@ const (
 )
 
 // projectRunFiles are the files managed during a run (used by clean command)
-var projectRunFiles = []string{"prd.json", "prd.md", "progress.txt", ".last-branch"}
+var projectRunFiles = []string{"prd.json", "prd.md", "progress.txt", ".last-branch", "run-state.json"}
 
 // HelpText is the CLI help message
 const HelpText = `Ralph - Autonomous Agent CLI
@@ -42,21 +43,32 @@ Commands:
   project-dir  Print full project directory path
   init         Initialize Ralph for current project
   run [n]      Run autonomous loop (default: 25 iterations)
+               --dry-run  print agent command, env and prompt per iteration
+  prompt       Preview the rendered prompt (--story ID for a specific story)
   status       Show current project status
   prd          Launch Claude for PRD creation
+  progress     Show progress.txt summary (patterns, compact)
   list         List all projects with archive info
-  logs         View run logs
-  archive      Manually archive current run
+  logs         View run logs (--project, --branch, --since, --grep, --json, --follow)
+  archive      Manually archive current run (list, show, restore)
+  gc           Remove archives and logs outside the retention policy
   clean        Remove project data (--all for everything)
 
 Examples:
-  ralph              # Interactive mode
-  ralph home         # Print RALPH_HOME path
-  ralph project-dir  # Print project directory path
-  ralph run          # Run with 25 iterations
-  ralph run 5        # Run with 5 iterations
-  ralph logs         # View run logs
-  ralph clean --all  # Remove all project data
+  ralph                         # Interactive mode
+  ralph home                    # Print RALPH_HOME path
+  ralph project-dir             # Print project directory path
+  ralph run                     # Run with 25 iterations
+  ralph run 5                   # Run with 5 iterations
+  ralph run --dry-run           # Show what each iteration would send
+  ralph prompt --story US-002   # Preview the prompt for a story
+  ralph progress compact        # Fold old progress entries into patterns
+  ralph logs                    # View run logs
+  ralph logs --grep ERROR       # Search all logs for errors
+  ralph logs --follow           # Watch the active run from another terminal
+  ralph archive restore <name>  # Make an archived run active again
+  ralph gc --dry-run            # Show what the retention policy would remove
+  ralph clean --all             # Remove all project data
 `
 
 // Home prints the RALPH_HOME path
@@ -184,8 +196,12 @@ func Setup() error {
 		return fmt.Errorf("failed to create projects directory: %w", err)
 	}
 
-	// Save config
-	cfg := &config.Config{RalphHome: absPath}
+	// Save config (keeping any other settings already configured)
+	cfg := &config.Config{}
+	if existingCfg != nil {
+		cfg = existingCfg
+	}
+	cfg.RalphHome = absPath
 	if err := config.Save(cfg); err != nil {
 		return err
 	}
@@ -454,6 +470,10 @@ func Status() error {
 			fmt.Println(format.FormatKeyValue("Stories", storiesText))
 		}
 
+		if blocked := p.BlockedCount(); blocked > 0 {
+			fmt.Println(format.FormatKeyValue("Blocked", styles.WarningText.Render(fmt.Sprintf("%d stories (attempt limit reached)", blocked))))
+		}
+
 		// Progress bar
 		if total > 0 {
 			prog := progress.New(progress.WithDefaultGradient(), progress.WithWidth(30), progress.WithoutPercentage())
@@ -526,58 +546,22 @@ func Archive() error {
 		return fmt.Errorf("no prd.json found - nothing to archive")
 	}
 
-	p, err := prd.Load(projectDir)
+	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
 
-	// Create archive directory with timestamp and branch name
-	branchName := strings.TrimPrefix(p.BranchName, "ralph/")
-	branchName = strings.ReplaceAll(branchName, "/", "-")
-	timestamp := time.Now().Format("2006-01-02")
-	archiveName := fmt.Sprintf("%s-%s", timestamp, branchName)
-	archiveDir := filepath.Join(projectDir, "archive", archiveName)
-
-	if err := os.MkdirAll(archiveDir, 0755); err != nil {
-		return err
-	}
-
-	// Copy files to archive
-	filesToArchive := []string{"prd.json", "progress.txt", "prd.md"}
-	archivedFiles := []string{}
-	for _, file := range filesToArchive {
-		src := filepath.Join(projectDir, file)
-		if _, err := os.Stat(src); err == nil {
-			data, err := os.ReadFile(src)
-			if err != nil {
-				continue
-			}
-			dst := filepath.Join(archiveDir, file)
-			if err := os.WriteFile(dst, data, 0644); err != nil {
-				return err
-			}
-			archivedFiles = append(archivedFiles, file)
-		}
-	}
-
-	// Reset progress.txt
 	cwd, _ := os.Getwd()
-	header := fmt.Sprintf("# Progress Log\n# Project: %s\n# Reset: %s\n\n", cwd, time.Now().Format("2006-01-02 15:04:05"))
-	progressPath := filepath.Join(projectDir, "progress.txt")
-	if err := os.WriteFile(progressPath, []byte(header), 0644); err != nil {
+	entry, err := archive.Create(projectDir, cwd, cfg.CompressArchives())
+	if err != nil {
 		return err
 	}
 
-	// Remove PRD files
-	os.Remove(filepath.Join(projectDir, "prd.json"))
-	os.Remove(filepath.Join(projectDir, "prd.md"))
-	os.Remove(filepath.Join(projectDir, ".last-branch"))
-
 	// Display success
 	fmt.Println(format.FormatSuccess("Archive created"))
 	fmt.Println()
-	fmt.Println(format.FormatKeyValue("Archived", strings.Join(archivedFiles, ", ")))
-	fmt.Println(format.FormatKeyValue("Location", archiveDir))
+	fmt.Println(format.FormatKeyValue("Archived", strings.Join(entry.Files, ", ")))
+	fmt.Println(format.FormatKeyValue("Location", entry.Path))
 	fmt.Println()
 	fmt.Println(format.FormatNextStep("/prd", "in Claude to start a new feature"))
 
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kento/ralph/internal/archive"
+	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/progress"
+	"github.com/kento/ralph/internal/project"
+	"github.com/kento/ralph/internal/ui/format"
+	"github.com/kento/ralph/internal/ui/styles"
+)
+
+// GC applies the archive retention policy to every project, removing old
+// archives, logs and progress backups. With dryRun it only lists what would be removed.
+func GC(dryRun bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	policy := archive.Policy{Keep: cfg.ArchiveKeep, MaxAge: cfg.ArchiveMaxAge()}
+	if !policy.IsSet() {
+		fmt.Println(format.FormatWarning("No retention policy configured, nothing to do."))
+		fmt.Println()
+		fmt.Println(format.FormatNextStep("Set archive_keep or archive_max_age_days", "in ~/.config/ralph/config.json"))
+		return nil
+	}
+
+	projects, err := project.ListProjects()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Println(format.FormatHeader("Garbage Collection (dry run)"))
+	} else {
+		fmt.Println(format.FormatHeader("Garbage Collection"))
+	}
+	fmt.Println()
+
+	now := time.Now()
+	var total int64
+	var archives, logs, backups int
+	for _, projectID := range projects {
+		projectDir := filepath.Join(cfg.RalphHome, "projects", projectID)
+
+		removals, err := archive.Collect(projectDir, policy, now)
+		if err != nil {
+			return fmt.Errorf("%s: %w", projectID, err)
+		}
+		if len(removals) == 0 {
+			continue
+		}
+
+		fmt.Println(format.FormatSection(extractDisplayName(projectDir, projectID), 50))
+		for _, r := range removals {
+			name := r.Archive
+			if name == "" {
+				rel, _ := filepath.Rel(projectDir, r.Path)
+				name = rel
+				if strings.HasPrefix(rel, progress.BackupDir+string(filepath.Separator)) {
+					backups++
+				} else {
+					logs++
+				}
+			} else {
+				archives++
+			}
+			fmt.Println(format.FormatBullet(fmt.Sprintf("%s %s", name, styles.Muted.Render("("+formatBytes(r.Bytes)+")"))))
+		}
+		fmt.Println()
+
+		if dryRun {
+			for _, r := range removals {
+				total += r.Bytes
+			}
+			continue
+		}
+
+		reclaimed, err := archive.Remove(projectDir, removals)
+		total += reclaimed
+		if err != nil {
+			return fmt.Errorf("%s: %w", projectID, err)
+		}
+	}
+
+	if archives == 0 && logs == 0 && backups == 0 {
+		fmt.Println(styles.Muted.Render("Nothing to remove."))
+		return nil
+	}
+
+	summary := fmt.Sprintf("%d archives, %d logs", archives, logs)
+	if backups > 0 {
+		summary += fmt.Sprintf(", %d progress backups", backups)
+	}
+	if dryRun {
+		fmt.Println(format.FormatKeyValue("Would remove", summary))
+		fmt.Println(format.FormatKeyValue("Would reclaim", formatBytes(total)))
+		fmt.Println()
+		fmt.Println(format.FormatNextStep("ralph gc", "to delete them"))
+		return nil
+	}
+
+	fmt.Println(format.FormatSuccess(fmt.Sprintf("Removed %s", summary)))
+	fmt.Println(format.FormatKeyValue("Reclaimed", formatBytes(total)))
+	return nil
+}
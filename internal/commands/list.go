@@ -8,6 +8,7 @@ import (
 
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/kento/ralph/internal/archive"
 	"github.com/kento/ralph/internal/config"
 	"github.com/kento/ralph/internal/prd"
 	"github.com/kento/ralph/internal/project"
@@ -99,7 +100,13 @@ func List() error {
 		rows = append(rows, table.Row{name, branch, stories, archives})
 	}
 
-	// Create and style the table
+	fmt.Println(renderTable(columns, rows))
+
+	return nil
+}
+
+// renderTable renders a static table with the standard Ralph styling
+func renderTable(columns []table.Column, rows []table.Row) string {
 	s := table.DefaultStyles()
 	s.Header = s.Header.
 		BorderStyle(lipgloss.NormalBorder()).
@@ -120,9 +127,7 @@ func List() error {
 		table.WithStyles(s),
 	)
 
-	fmt.Println(t.View())
-
-	return nil
+	return t.View()
 }
 
 func getProjectInfo(ralphHome, projectID string) projectInfo {
@@ -147,15 +152,9 @@ func getProjectInfo(ralphHome, projectID string) projectInfo {
 		}
 	}
 
-	// Count archives
-	archiveDir := filepath.Join(projectDir, "archive")
-	entries, err := os.ReadDir(archiveDir)
-	if err == nil {
-		for _, e := range entries {
-			if e.IsDir() {
-				info.archiveCount++
-			}
-		}
+	// Count archives from the archive index
+	if entries, err := archive.List(projectDir); err == nil {
+		info.archiveCount = len(entries)
 	}
 
 	return info
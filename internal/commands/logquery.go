@@ -0,0 +1,372 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/project"
+	"github.com/kento/ralph/internal/runlog"
+	"github.com/kento/ralph/internal/ui/format"
+	"github.com/kento/ralph/internal/ui/styles"
+)
+
+// logsUsage lists the logs flags
+const logsUsage = "Usage: ralph logs [--project X] [--branch Y] [--since 2d] [--grep pattern] [--json] | --follow"
+
+// logFollowInterval is how often --follow checks the log for new output
+const logFollowInterval = 500 * time.Millisecond
+
+// logQuery selects logs for the non-interactive logs command
+type logQuery struct {
+	project string
+	branch  string
+	since   time.Duration
+	grep    *regexp.Regexp
+}
+
+// logMatch is a log line matching --grep
+type logMatch struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// logResult is a log in the output of the logs command
+type logResult struct {
+	Project string     `json:"project"`
+	Branch  string     `json:"branch,omitempty"`
+	Archive string     `json:"archive,omitempty"`
+	Started time.Time  `json:"started"`
+	Size    int64      `json:"size"`
+	Path    string     `json:"path"`
+	Matches []logMatch `json:"matches,omitempty"`
+}
+
+// LogsCommand lists, searches or follows logs. Without arguments it opens
+// the interactive log picker.
+func LogsCommand(args []string) error {
+	if len(args) == 0 {
+		return Logs()
+	}
+
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	projectFlag := fs.String("project", "", "")
+	branchFlag := fs.String("branch", "", "")
+	sinceFlag := fs.String("since", "", "")
+	grepFlag := fs.String("grep", "", "")
+	jsonFlag := fs.Bool("json", false, "")
+	followFlag := fs.Bool("follow", false, "")
+	fs.BoolVar(followFlag, "f", false, "")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%v\n%s", err, logsUsage)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument: %s\n%s", fs.Arg(0), logsUsage)
+	}
+
+	ralphHome, err := config.GetRalphHome()
+	if err != nil {
+		return err
+	}
+
+	if *followFlag {
+		return followLogs(ralphHome, *projectFlag)
+	}
+
+	query := logQuery{project: *projectFlag, branch: *branchFlag}
+	if *sinceFlag != "" {
+		if query.since, err = parseSince(*sinceFlag); err != nil {
+			return err
+		}
+	}
+	if *grepFlag != "" {
+		if query.grep, err = regexp.Compile(*grepFlag); err != nil {
+			return fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+	}
+
+	logs, err := findAllLogs(ralphHome)
+	if err != nil {
+		return err
+	}
+
+	results, err := query.run(ralphHome, logs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *jsonFlag {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(results) == 0 {
+		fmt.Println(styles.Muted.Render("No matching logs."))
+		return nil
+	}
+	if query.grep != nil {
+		printLogMatches(results)
+	} else {
+		printLogTable(results)
+	}
+	return nil
+}
+
+// parseSince parses a --since duration. Besides Go durations (90m, 12h)
+// it accepts days and weeks: 2d, 1w.
+func parseSince(value string) (time.Duration, error) {
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if n := len(value); n > 1 && unit[value[n-1]] > 0 {
+		count, err := strconv.Atoi(value[:n-1])
+		if err == nil && count >= 0 {
+			return time.Duration(count) * unit[value[n-1]], nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid --since value %q (use e.g. 2d, 12h, 1w)", value)
+}
+
+// matchesProject reports whether a log belongs to the --project value: the
+// whole project ID, or the end of the project path at a directory boundary
+// ("webapp", "code/webapp"), ignoring case
+func (q logQuery) matchesProject(ralphHome string, log logItem) bool {
+	if q.project == "" || strings.EqualFold(log.project, q.project) {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(ralphHome, "projects", log.project, ".path"))
+	if err != nil {
+		return false
+	}
+	path := strings.ToLower(filepath.ToSlash(strings.TrimSpace(string(data))))
+	needle := strings.ToLower(strings.Trim(filepath.ToSlash(q.project), "/"))
+	return needle != "" && (strings.Trim(path, "/") == needle || strings.HasSuffix(path, "/"+needle))
+}
+
+// matchesBranch reports whether a log is of the --branch value, with or
+// without the "ralph/" prefix
+func (q logQuery) matchesBranch(log logItem) bool {
+	return q.branch == "" || log.branch == q.branch || strings.TrimPrefix(log.branch, "ralph/") == q.branch
+}
+
+// run filters logs and searches them for the --grep pattern
+func (q logQuery) run(ralphHome string, logs []logItem, now time.Time) ([]logResult, error) {
+	results := []logResult{}
+	for _, log := range logs {
+		if !q.matchesProject(ralphHome, log) || !q.matchesBranch(log) {
+			continue
+		}
+		if q.since > 0 && now.Sub(log.started) > q.since && now.Sub(log.modTime) > q.since {
+			continue
+		}
+
+		result := logResult{
+			Project: extractShortProjectName(ralphHome, log.project),
+			Branch:  log.branch,
+			Archive: log.archive,
+			Started: log.started,
+			Size:    log.size,
+			Path:    log.path,
+		}
+
+		if q.grep != nil {
+			matches, err := grepLog(log.path, q.grep)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				continue
+			}
+			result.Matches = matches
+		}
+
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// grepLog returns the lines of a log matching pattern, without ANSI codes
+func grepLog(path string, pattern *regexp.Regexp) ([]logMatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var matches []logMatch
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := ansi.Strip(scanner.Text())
+		if pattern.MatchString(line) {
+			matches = append(matches, logMatch{Line: n, Text: line})
+		}
+	}
+	return matches, scanner.Err()
+}
+
+// printLogTable prints the selected logs
+func printLogTable(results []logResult) {
+	fmt.Println(format.FormatHeader(fmt.Sprintf("Logs (%d)", len(results))))
+	fmt.Println()
+
+	pathWidth := 4
+	for _, r := range results {
+		pathWidth = max(pathWidth, len(r.Path))
+	}
+
+	columns := []table.Column{
+		{Title: "Started", Width: 16},
+		{Title: "Project", Width: 16},
+		{Title: "Branch", Width: 24},
+		{Title: "Size", Width: 9},
+		{Title: "Path", Width: pathWidth},
+	}
+
+	rows := []table.Row{}
+	for _, r := range results {
+		branch := r.Branch
+		if r.Archive != "" {
+			branch += " (archived)"
+		}
+		rows = append(rows, table.Row{r.Started.Format("2006-01-02 15:04"), r.Project, branch, formatBytes(r.Size), r.Path})
+	}
+
+	fmt.Println(renderTable(columns, rows))
+}
+
+// printLogMatches prints the --grep matches grouped by log
+func printLogMatches(results []logResult) {
+	total := 0
+	for _, r := range results {
+		fmt.Println(format.FormatSection(fmt.Sprintf("%s %s %s", r.Project, r.Branch, r.Started.Format("2006-01-02 15:04")), 70))
+		fmt.Println(styles.Muted.Render(r.Path))
+		for _, m := range r.Matches {
+			fmt.Printf("%s %s\n", styles.Muted.Render(fmt.Sprintf("%6d", m.Line)), m.Text)
+		}
+		fmt.Println()
+		total += len(r.Matches)
+	}
+	fmt.Println(format.FormatKeyValue("Matches", fmt.Sprintf("%d in %d logs", total, len(results))))
+}
+
+// followLogs prints the active run's log of a project as it is written,
+// switching to the next run's log when it starts. Runs until interrupted.
+func followLogs(ralphHome, projectFilter string) error {
+	projectDir, err := followProjectDir(ralphHome, projectFilter)
+	if err != nil {
+		return err
+	}
+	logsDir := filepath.Join(projectDir, "logs")
+
+	var current string
+	var offset int64
+	waiting := false
+	for {
+		if newest := activeLog(logsDir); newest != "" && newest != current {
+			if current != "" {
+				fmt.Println()
+			}
+			fmt.Println(format.FormatSection(filepath.Base(newest), 70))
+			current, offset, waiting = newest, 0, false
+		} else if current == "" && !waiting {
+			fmt.Println(styles.Muted.Render("Waiting for a run log in " + logsDir + " ..."))
+			waiting = true
+		}
+
+		if current != "" {
+			if offset, err = copyFrom(os.Stdout, current, offset); err != nil {
+				return err
+			}
+		}
+		time.Sleep(logFollowInterval)
+	}
+}
+
+// followProjectDir returns the project to follow: the one matching
+// --project, or the current directory's project
+func followProjectDir(ralphHome, projectFilter string) (string, error) {
+	if projectFilter == "" {
+		return project.GetProjectDir()
+	}
+
+	projects, err := project.ListProjects()
+	if err != nil {
+		return "", err
+	}
+	query := logQuery{project: projectFilter}
+	var found []string
+	for _, id := range projects {
+		if query.matchesProject(ralphHome, logItem{project: id}) {
+			found = append(found, id)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no project matches %q", projectFilter)
+	case 1:
+		return filepath.Join(ralphHome, "projects", found[0]), nil
+	default:
+		return "", fmt.Errorf("%q matches %d projects: %s", projectFilter, len(found), strings.Join(found, ", "))
+	}
+}
+
+// activeLog returns the log the active run writes to, or else the most
+// recently modified log in a logs directory
+func activeLog(logsDir string) string {
+	if marker, err := runlog.Active(logsDir); err == nil && marker.Log != "" {
+		return marker.Log
+	}
+
+	var newest logItem
+	for _, log := range scanLogs(logsDir, "", "") {
+		if log.modTime.After(newest.modTime) {
+			newest = log
+		}
+	}
+	return newest.path
+}
+
+// copyFrom writes the contents of path after offset to w and returns the
+// new offset. A file that shrank is printed again from the start.
+func copyFrom(w io.Writer, path string, offset int64) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return offset, nil
+		}
+		return offset, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	n, err := io.Copy(w, f)
+	return offset + n, err
+}
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		{"90m", 90 * time.Minute, false},
+		{"12h", 12 * time.Hour, false},
+		{"2d", 48 * time.Hour, false},
+		{"1w", 7 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"d", 0, true},
+		{"-1d", 0, true},
+		{"-5m", 0, true},
+		{"2x", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.value)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseSince(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	home := t.TempDir()
+	now := time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC)
+
+	// Projects are found by ID or by the path in their .path file
+	for id, path := range map[string]string{"users-kim-webapp": "/Users/Kim/WebApp", "srv-rapid-api": "/srv/rapid-api"} {
+		os.MkdirAll(filepath.Join(home, "projects", id), 0755)
+		os.WriteFile(filepath.Join(home, "projects", id, ".path"), []byte(path+"\n"), 0644)
+	}
+	apiLog := filepath.Join(home, "api.log")
+	webLog := filepath.Join(home, "web.log")
+	oldLog := filepath.Join(home, "old.log")
+	rapidLog := filepath.Join(home, "rapid.log")
+	os.WriteFile(apiLog, []byte("build ok\nFAIL TestParse\n"), 0644)
+	os.WriteFile(webLog, []byte("\x1b[31mFAIL\x1b[0m TestBadge\n"), 0644)
+	os.WriteFile(oldLog, []byte("FAIL TestOld\n"), 0644)
+	os.WriteFile(rapidLog, []byte("build ok\n"), 0644)
+
+	logs := []logItem{
+		{path: apiLog, project: "api", branch: "ralph/auth", started: now.Add(-2 * time.Hour), modTime: now.Add(-time.Hour)},
+		{path: webLog, project: "users-kim-webapp", branch: "ralph/badge", started: now.Add(-30 * time.Hour), modTime: now.Add(-time.Hour)},
+		{path: oldLog, project: "api", branch: "feature/auth", started: now.Add(-72 * time.Hour), modTime: now.Add(-72 * time.Hour)},
+		{path: rapidLog, project: "srv-rapid-api", branch: "main", started: now.Add(-100 * time.Hour), modTime: now.Add(-100 * time.Hour)},
+	}
+
+	tests := []struct {
+		name  string
+		query logQuery
+		want  []string
+	}{
+		{"all", logQuery{}, []string{"api.log", "web.log", "old.log", "rapid.log"}},
+		{"project ID, any case", logQuery{project: "API"}, []string{"api.log", "old.log"}},
+		{"whole project ID only", logQuery{project: "rapid"}, nil},
+		{"project ID", logQuery{project: "srv-rapid-api"}, []string{"rapid.log"}},
+		{"project directory", logQuery{project: "rapid-api"}, []string{"rapid.log"}},
+		{"project path", logQuery{project: "kim/webapp"}, []string{"web.log"}},
+		{"partial directory name", logQuery{project: "im/webapp"}, nil},
+		{"branch", logQuery{branch: "ralph/auth"}, []string{"api.log"}},
+		{"branch without ralph/", logQuery{branch: "auth"}, []string{"api.log"}},
+		{"other branch prefix", logQuery{branch: "feature/auth"}, []string{"old.log"}},
+		{"since start", logQuery{since: 3 * time.Hour}, []string{"api.log", "web.log"}},
+		{"since", logQuery{since: 30 * time.Minute}, nil},
+		{"grep", logQuery{grep: regexp.MustCompile(`^FAIL Test`)}, []string{"api.log", "web.log", "old.log"}},
+		{"grep and project", logQuery{project: "API", grep: regexp.MustCompile(`Parse`)}, []string{"api.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := tt.query.run(home, logs, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, filepath.Base(r.Path))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("logs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Matches carry their line number, without ANSI codes
+	results, _ := logQuery{grep: regexp.MustCompile(`FAIL`)}.run(home, logs[:2], now)
+	if m := results[0].Matches; len(m) != 1 || m[0].Line != 2 || m[0].Text != "FAIL TestParse" {
+		t.Errorf("api.log matches = %+v", m)
+	}
+	if m := results[1].Matches; len(m) != 1 || m[0].Text != "FAIL TestBadge" {
+		t.Errorf("web.log matches = %+v", m)
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	copyAll := func(offset int64) (string, int64) {
+		t.Helper()
+		var b bytes.Buffer
+		next, err := copyFrom(&b, path, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.String(), next
+	}
+
+	// A log that isn't there yet prints nothing
+	if out, offset := copyAll(0); out != "" || offset != 0 {
+		t.Errorf("missing log: %q at %d", out, offset)
+	}
+
+	os.WriteFile(path, []byte("first\n"), 0644)
+	out, offset := copyAll(0)
+	if out != "first\n" || offset != 6 {
+		t.Errorf("first copy: %q at %d", out, offset)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("second\n")
+	f.Close()
+	if out, offset = copyAll(offset); out != "second\n" || offset != 13 {
+		t.Errorf("appended copy: %q at %d", out, offset)
+	}
+
+	// A truncated log is printed again from the start
+	os.WriteFile(path, []byte("new\n"), 0644)
+	if out, offset = copyAll(offset); out != "new\n" || offset != 4 {
+		t.Errorf("copy after truncation: %q at %d", out, offset)
+	}
+}
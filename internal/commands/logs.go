@@ -4,14 +4,15 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/runlog"
 	"github.com/kento/ralph/internal/ui/format"
 	"github.com/kento/ralph/internal/ui/styles"
 )
@@ -21,9 +22,14 @@ type logItem struct {
 	displayName string
 	project     string
 	date        string
+	branch      string    // Branch the run was on, from the file name
+	archive     string    // Archive ID for logs moved into an archive
+	started     time.Time // Run start from the file name, else the file's mod time
+	modTime     time.Time
+	size        int64
 }
 
-func (i logItem) FilterValue() string { return i.displayName }
+func (i logItem) FilterValue() string { return i.date + " " + i.displayName }
 
 type logItemDelegate struct{}
 
@@ -65,6 +71,13 @@ func (m logsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		// While typing a filter every key belongs to the filter input, and
+		// esc clears an applied filter before it quits
+		if m.list.FilterState() == list.Filtering && msg.String() != "ctrl+c" ||
+			m.list.FilterState() == list.FilterApplied && msg.String() == "esc" {
+			break
+		}
+
 		switch keypress := msg.String(); keypress {
 		case "q", "ctrl+c", "esc":
 			m.quitting = true
@@ -102,7 +115,7 @@ func (m logsModel) View() string {
 	b.WriteString("\n\n")
 	b.WriteString(m.list.View())
 	b.WriteString("\n")
-	b.WriteString(pickerHelpStyle.Render("↑/↓ navigate • enter view • q quit"))
+	b.WriteString(pickerHelpStyle.Render("↑/↓ navigate • / filter • enter view • q quit"))
 	return b.String()
 }
 
@@ -139,29 +152,31 @@ func Logs() error {
 
 	l := list.New(items, logItemDelegate{}, 60, listHeight)
 	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(false)
+	l.SetFilteringEnabled(true)
 	l.SetShowTitle(false)
 	l.SetShowHelp(false)
 
-	m := logsModel{list: l}
+	for {
+		m := logsModel{list: l}
 
-	p := tea.NewProgram(m)
-	finalModel, err := p.Run()
-	if err != nil {
-		return fmt.Errorf("error running log picker: %w", err)
-	}
+		p := tea.NewProgram(m)
+		finalModel, err := p.Run()
+		if err != nil {
+			return fmt.Errorf("error running log picker: %w", err)
+		}
 
-	fm := finalModel.(logsModel)
-	if fm.quitting || fm.choice == "" {
-		return nil
-	}
+		fm := finalModel.(logsModel)
+		if fm.quitting || fm.choice == "" {
+			return nil
+		}
 
-	// Display the selected log using cat (renders ANSI codes)
-	fmt.Println()
-	cmd := exec.Command("cat", fm.choice)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+		// Page through the selected log; esc comes back to the picker
+		back, err := viewLog(fm.choice)
+		if err != nil || !back {
+			return err
+		}
+		l = fm.list
+	}
 }
 
 // findAllLogs scans all projects for log files
@@ -184,43 +199,21 @@ func findAllLogs(ralphHome string) ([]logItem, error) {
 		}
 
 		projectID := entry.Name()
-		logsDir := filepath.Join(projectsDir, projectID, "logs")
-
-		// Walk the logs directory recursively (handles nested branch names)
-		filepath.WalkDir(logsDir, func(path string, d os.DirEntry, err error) error {
-			if err != nil {
-				return nil // Skip errors
-			}
-			if d.IsDir() {
-				return nil
-			}
-			if !strings.HasSuffix(path, ".log") {
-				return nil
-			}
-
-			// Extract display name from path
-			relPath, _ := filepath.Rel(logsDir, path)
-			displayName := strings.TrimSuffix(relPath, ".log")
-
-			// Extract date from filename (format: name_2026-01-11.log)
-			date := ""
-			parts := strings.Split(filepath.Base(path), "_")
-			if len(parts) >= 2 {
-				date = strings.TrimSuffix(parts[len(parts)-1], ".log")
+		projectDir := filepath.Join(projectsDir, projectID)
+		projectDisplay := extractShortProjectName(ralphHome, projectID)
+
+		logs = append(logs, scanLogs(filepath.Join(projectDir, "logs"), projectID, projectDisplay)...)
+
+		// Logs moved into (uncompressed) archives
+		archiveDirs, _ := filepath.Glob(filepath.Join(projectDir, "archive", "*", "logs"))
+		for _, logsDir := range archiveDirs {
+			id := filepath.Base(filepath.Dir(logsDir))
+			archived := scanLogs(logsDir, projectID, projectDisplay+"/archive/"+id)
+			for i := range archived {
+				archived[i].archive = id
 			}
-
-			// Get project display name
-			projectDisplay := extractShortProjectName(ralphHome, projectID)
-
-			logs = append(logs, logItem{
-				path:        path,
-				displayName: fmt.Sprintf("%s/%s", projectDisplay, displayName),
-				project:     projectID,
-				date:        date,
-			})
-
-			return nil
-		})
+			logs = append(logs, archived...)
+		}
 	}
 
 	// Sort by date (newest first)
@@ -231,6 +224,56 @@ func findAllLogs(ralphHome string) ([]logItem, error) {
 	return logs, nil
 }
 
+// scanLogs walks a logs directory recursively (handles nested branch names)
+func scanLogs(logsDir, projectID, displayPrefix string) []logItem {
+	var logs []logItem
+	filepath.WalkDir(logsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(path, ".log") {
+			return nil
+		}
+
+		// Extract display name from path
+		relPath, _ := filepath.Rel(logsDir, path)
+		displayName := strings.TrimSuffix(relPath, ".log")
+
+		// Extract date from filename (format: name_2026-01-11.log)
+		date := ""
+		parts := strings.Split(filepath.Base(path), "_")
+		if len(parts) >= 2 {
+			date = strings.TrimSuffix(parts[len(parts)-1], ".log")
+		}
+
+		item := logItem{
+			path:        path,
+			displayName: fmt.Sprintf("%s/%s", displayPrefix, displayName),
+			project:     projectID,
+			date:        date,
+		}
+		if i := strings.LastIndex(displayName, "_"); i > 0 {
+			item.branch = filepath.ToSlash(displayName[:i])
+		}
+		if info, err := d.Info(); err == nil {
+			item.modTime = info.ModTime()
+			item.size = info.Size()
+		}
+		item.started = item.modTime
+		if t, err := time.ParseInLocation(runlog.DateFormat, date, time.Local); err == nil {
+			item.started = t
+		}
+
+		logs = append(logs, item)
+
+		return nil
+	})
+	return logs
+}
+
 // extractShortProjectName returns a short display name for the project
 func extractShortProjectName(ralphHome, projectID string) string {
 	projectDir := filepath.Join(ralphHome, "projects", projectID)
@@ -0,0 +1,439 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/kento/ralph/internal/ui/styles"
+)
+
+// logLineKind classifies a line of a run log
+type logLineKind int
+
+const (
+	logText    logLineKind = iota
+	logSection             // "Ralph →" prompt that starts an iteration
+	logTool                // Tool call
+	logError               // ERROR line or output of a failed tool call
+)
+
+// logFilter limits the log viewer to some kinds of lines
+type logFilter int
+
+const (
+	filterAll logFilter = iota
+	filterTools
+	filterErrors
+)
+
+func (f logFilter) String() string {
+	switch f {
+	case filterTools:
+		return "tool calls"
+	case filterErrors:
+		return "errors"
+	default:
+		return "all"
+	}
+}
+
+// logLine is a line of a run log with its text stripped of ANSI codes
+type logLine struct {
+	styled string
+	plain  string
+	kind   logLineKind
+}
+
+var (
+	logMatchStyle   = lipgloss.NewStyle().Background(styles.Warning).Foreground(lipgloss.Color("#000000"))
+	logCurrentStyle = lipgloss.NewStyle().Background(styles.Primary).Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
+	logStatusStyle  = lipgloss.NewStyle().PaddingLeft(2).Foreground(styles.FgMuted)
+)
+
+// parseLogLines splits a run log into classified lines
+func parseLogLines(content string) []logLine {
+	raw := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	lines := make([]logLine, len(raw))
+	for i, styled := range raw {
+		plain := ansi.Strip(styled)
+		// Sub-agent output is indented behind "│" guides
+		trimmed := strings.TrimLeft(strings.TrimSpace(plain), "│ ")
+
+		kind := logText
+		switch {
+		case strings.HasPrefix(trimmed, "Ralph "+styles.Arrow):
+			kind = logSection
+		case strings.HasPrefix(trimmed, styles.ToolPending+" "):
+			kind = logTool
+		case strings.HasPrefix(trimmed, "ERROR "), strings.HasPrefix(trimmed, styles.ToolOutput+" "):
+			kind = logError
+		}
+		lines[i] = logLine{styled: styled, plain: plain, kind: kind}
+	}
+	return lines
+}
+
+// logViewModel pages through a single run log
+type logViewModel struct {
+	title    string
+	lines    []logLine
+	visible  []int // Indices into lines shown with the current filter
+	filter   logFilter
+	viewport viewport.Model
+	ready    bool
+
+	search    textinput.Model
+	searching bool
+	query     string
+	matches   []int // Positions in visible that match the query
+	match     int   // Current match in matches
+
+	back     bool // Return to the log picker
+	quitting bool
+}
+
+func newLogViewModel(title, content string) logViewModel {
+	search := textinput.New()
+	search.Prompt = "/"
+	search.Placeholder = "search"
+
+	m := logViewModel{
+		title:  title,
+		lines:  parseLogLines(content),
+		search: search,
+	}
+	m.applyFilter()
+	return m
+}
+
+func (m logViewModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m logViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		height := max(msg.Height-5, 1)
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width, height)
+			m.ready = true
+		} else {
+			m.viewport.Width = msg.Width
+			m.viewport.Height = height
+		}
+		m.render()
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+
+		switch msg.String() {
+		case "q", "ctrl+c":
+			m.quitting = true
+			return m, tea.Quit
+		case "esc":
+			if m.query != "" {
+				m.setQuery("")
+				return m, nil
+			}
+			m.back = true
+			return m, tea.Quit
+		case "/":
+			m.searching = true
+			m.search.SetValue(m.query)
+			m.search.CursorEnd()
+			return m, m.search.Focus()
+		case "n":
+			m.nextMatch(1)
+			return m, nil
+		case "N":
+			m.nextMatch(-1)
+			return m, nil
+		case "]":
+			m.jumpSection(1)
+			return m, nil
+		case "[":
+			m.jumpSection(-1)
+			return m, nil
+		case "t":
+			m.toggleFilter(filterTools)
+			return m, nil
+		case "e":
+			m.toggleFilter(filterErrors)
+			return m, nil
+		case "g", "home":
+			m.viewport.GotoTop()
+			return m, nil
+		case "G", "end":
+			m.viewport.GotoBottom()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// updateSearch handles keys while the search prompt is open
+func (m logViewModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		return m, nil
+	case "enter":
+		m.searching = false
+		m.search.Blur()
+		m.setQuery(m.search.Value())
+		m.nextMatch(0)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	return m, cmd
+}
+
+// toggleFilter switches to filter, or back to all lines if it is active
+func (m *logViewModel) toggleFilter(filter logFilter) {
+	if m.filter == filter {
+		filter = filterAll
+	}
+	m.filter = filter
+	m.applyFilter()
+	m.viewport.GotoTop()
+}
+
+// applyFilter recomputes the visible lines and search matches
+func (m *logViewModel) applyFilter() {
+	m.visible = m.visible[:0]
+	for i, line := range m.lines {
+		switch m.filter {
+		case filterTools:
+			if line.kind != logTool && line.kind != logSection {
+				continue
+			}
+		case filterErrors:
+			if line.kind != logError && line.kind != logSection {
+				continue
+			}
+		}
+		m.visible = append(m.visible, i)
+	}
+	m.findMatches()
+	m.render()
+}
+
+// setQuery changes the search query and re-renders the highlights
+func (m *logViewModel) setQuery(query string) {
+	m.query = query
+	m.findMatches()
+	m.render()
+}
+
+// findMatches collects the visible lines containing the query
+func (m *logViewModel) findMatches() {
+	m.matches = m.matches[:0]
+	m.match = 0
+	if m.query == "" {
+		return
+	}
+	query := strings.ToLower(m.query)
+	for pos, i := range m.visible {
+		if strings.Contains(strings.ToLower(m.lines[i].plain), query) {
+			m.matches = append(m.matches, pos)
+		}
+	}
+}
+
+// nextMatch moves to the next (1) or previous (-1) match, or with 0 to the
+// first match at or below the top of the viewport
+func (m *logViewModel) nextMatch(dir int) {
+	if len(m.matches) == 0 {
+		return
+	}
+
+	switch dir {
+	case 0:
+		m.match = 0
+		for i, pos := range m.matches {
+			if pos >= m.viewport.YOffset {
+				m.match = i
+				break
+			}
+		}
+	default:
+		m.match = (m.match + dir + len(m.matches)) % len(m.matches)
+	}
+
+	m.render()
+	m.scrollTo(m.matches[m.match])
+}
+
+// jumpSection scrolls to the start of the next (1) or previous (-1) iteration
+func (m *logViewModel) jumpSection(dir int) {
+	top := m.viewport.YOffset
+	if dir > 0 {
+		for pos := top + 1; pos < len(m.visible); pos++ {
+			if m.lines[m.visible[pos]].kind == logSection {
+				m.viewport.SetYOffset(pos)
+				return
+			}
+		}
+		m.viewport.GotoBottom()
+		return
+	}
+	for pos := top - 1; pos >= 0; pos-- {
+		if m.lines[m.visible[pos]].kind == logSection {
+			m.viewport.SetYOffset(pos)
+			return
+		}
+	}
+	m.viewport.GotoTop()
+}
+
+// scrollTo brings a visible line into view, a third from the top
+func (m *logViewModel) scrollTo(pos int) {
+	if pos < m.viewport.YOffset || pos >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(max(pos-m.viewport.Height/3, 0))
+	}
+}
+
+// section returns the iteration (1-based) shown at the top of the viewport
+// and the number of iterations in the log
+func (m logViewModel) section() (int, int) {
+	current, total := 0, 0
+	for pos, i := range m.visible {
+		if m.lines[i].kind != logSection {
+			continue
+		}
+		total++
+		if pos <= m.viewport.YOffset {
+			current = total
+		}
+	}
+	return current, total
+}
+
+// render fills the viewport with the visible lines, highlighting matches
+func (m *logViewModel) render() {
+	if !m.ready {
+		return
+	}
+
+	current := -1
+	if len(m.matches) > 0 {
+		current = m.matches[m.match]
+	}
+
+	isMatch := make(map[int]bool, len(m.matches))
+	for _, pos := range m.matches {
+		isMatch[pos] = true
+	}
+
+	var b strings.Builder
+	for pos, i := range m.visible {
+		line := m.lines[i]
+		if isMatch[pos] {
+			style := logMatchStyle
+			if pos == current {
+				style = logCurrentStyle
+			}
+			b.WriteString(highlight(line.plain, m.query, style))
+		} else {
+			b.WriteString(line.styled)
+		}
+		b.WriteString("\n")
+	}
+	m.viewport.SetContent(b.String())
+}
+
+// highlight renders every case-insensitive occurrence of query in text
+func highlight(text, query string, style lipgloss.Style) string {
+	lower := strings.ToLower(text)
+	needle := strings.ToLower(query)
+	if needle == "" || len(lower) != len(text) {
+		return style.Render(text)
+	}
+
+	var b strings.Builder
+	for {
+		idx := strings.Index(lower, needle)
+		if idx < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:idx])
+		b.WriteString(style.Render(text[idx : idx+len(needle)]))
+		text, lower = text[idx+len(needle):], lower[idx+len(needle):]
+	}
+}
+
+func (m logViewModel) View() string {
+	if m.quitting || m.back {
+		return ""
+	}
+	if !m.ready {
+		return "\n  Loading..."
+	}
+
+	var b strings.Builder
+	b.WriteString(pickerTitleStyle.Render(m.title))
+	b.WriteString("\n\n")
+	b.WriteString(m.viewport.View())
+	b.WriteString("\n")
+
+	if m.searching {
+		b.WriteString("  " + m.search.View())
+		return b.String()
+	}
+
+	var status []string
+	if current, total := m.section(); total > 0 {
+		status = append(status, fmt.Sprintf("iteration %d/%d", max(current, 1), total))
+	}
+	if m.filter != filterAll {
+		status = append(status, "showing "+m.filter.String())
+	}
+	if m.query != "" {
+		if len(m.matches) == 0 {
+			status = append(status, fmt.Sprintf("no matches for %q", m.query))
+		} else {
+			status = append(status, fmt.Sprintf("match %d/%d for %q", m.match+1, len(m.matches), m.query))
+		}
+	}
+	status = append(status, fmt.Sprintf("%d%%", int(m.viewport.ScrollPercent()*100)))
+	b.WriteString(logStatusStyle.Render(strings.Join(status, " • ")))
+	b.WriteString("\n")
+	b.WriteString(logStatusStyle.Render("/ search • n/N match • [/] iteration • t tools • e errors • esc back • q quit"))
+	return b.String()
+}
+
+// viewLog opens a log file in the pager. Returns true when the user asked
+// to go back to the picker.
+func viewLog(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	m := newLogViewModel(filepath.Base(path), string(data))
+	finalModel, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return false, fmt.Errorf("error running log viewer: %w", err)
+	}
+	return finalModel.(logViewModel).back, nil
+}
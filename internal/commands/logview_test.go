@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/kento/ralph/internal/ui/format"
+)
+
+func TestLogViewFilterAndSearch(t *testing.T) {
+	setupTestEnv(t)
+
+	log := strings.Join([]string{
+		format.FormatPrompt("Work on US-001"),
+		format.FormatClaudeHeader(),
+		format.FormatToolCall(format.Tool{Name: "Read", Context: "prd.json"}),
+		"Reading the PRD first.",
+		format.FormatError("build failed"),
+		"",
+		format.FormatSection("Iteration 2", 40),
+		"",
+		format.FormatPrompt("Work on US-002"),
+		format.FormatClaudeHeader(),
+		format.FormatToolCall(format.Tool{Name: "Bash", Context: "go build ./..."}),
+		format.FormatDone("Success in 3.0s"),
+	}, "\n")
+
+	m := newLogViewModel("feature_2026-01-11.log", log)
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 40))
+
+	// Only tool calls (plus the iteration starts), then search within them
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	tm.Type("bash")
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+
+	fm := tm.FinalModel(t, teatest.WithFinalTimeout(time.Second)).(logViewModel)
+
+	if fm.filter != filterTools {
+		t.Errorf("filter = %v, want tool calls", fm.filter)
+	}
+	// Two prompts and two tool calls
+	if len(fm.visible) != 4 {
+		t.Errorf("visible lines = %d, want 4", len(fm.visible))
+	}
+	if len(fm.matches) != 1 || fm.lines[fm.visible[fm.matches[0]]].kind != logTool {
+		t.Errorf("matches = %v, want the Bash tool call", fm.matches)
+	}
+	if _, total := fm.section(); total != 2 {
+		t.Errorf("iterations = %d, want 2", total)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package commands
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, where only the agent itself is stopped
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup stops the process pid. Windows has no SIGTERM, so
+// this kills it right away.
+func terminateProcessGroup(pid int) error {
+	return killProcessGroup(pid)
+}
+
+// killProcessGroup kills the process pid
+func killProcessGroup(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// processGroupAlive always reports false: terminateProcessGroup already
+// killed the process, so there is no grace period to wait out
+func processGroupAlive(pid int) bool {
+	return false
+}
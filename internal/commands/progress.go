@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/progress"
+	"github.com/kento/ralph/internal/project"
+	"github.com/kento/ralph/internal/ui/format"
+	"github.com/kento/ralph/internal/ui/styles"
+)
+
+// loadProgress loads the current project's progress.txt
+func loadProgress() (string, *progress.Log, error) {
+	projectDir, err := project.GetProjectDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	log, err := progress.Load(projectDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("no progress.txt found. Run 'ralph init' first")
+		}
+		return "", nil, err
+	}
+	return projectDir, log, nil
+}
+
+// ProgressShow prints a summary of the current project's progress.txt
+func ProgressShow() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	projectDir, log, err := loadProgress()
+	if err != nil {
+		return err
+	}
+
+	size := len(log.String())
+	if info, err := os.Stat(progress.Path(projectDir)); err == nil {
+		size = int(info.Size())
+	}
+
+	fmt.Println(format.FormatHeader("Progress Log"))
+	fmt.Println()
+	fmt.Println(format.FormatKeyValue("Path", progress.Path(projectDir)))
+
+	sizeText := fmt.Sprintf("%s / %s budget", formatBytes(int64(size)), formatBytes(int64(cfg.ProgressBudget())))
+	if size > cfg.ProgressBudget() {
+		sizeText = styles.WarningText.Render(sizeText)
+	}
+	fmt.Println(format.FormatKeyValue("Size", sizeText))
+	fmt.Println(format.FormatKeyValue("Entries", fmt.Sprintf("%d", len(log.Entries))))
+	fmt.Println(format.FormatKeyValue("Patterns", fmt.Sprintf("%d", len(log.Patterns))))
+	fmt.Println(format.FormatKeyValue("Issues", fmt.Sprintf("%d open / %d total", log.OpenIssues(), len(log.Issues))))
+	if len(log.History) > 0 {
+		fmt.Println(format.FormatKeyValue("Compacted", fmt.Sprintf("%d entries", len(log.History))))
+	}
+
+	if len(log.Entries) > 0 {
+		fmt.Println()
+		fmt.Println(styles.Muted.Render("Recent entries:"))
+		start := max(len(log.Entries)-5, 0)
+		for _, entry := range log.Entries[start:] {
+			fmt.Println(format.FormatBullet(entry.Summary()))
+		}
+	}
+
+	if open := log.OpenIssues(); open > 0 {
+		fmt.Println()
+		fmt.Println(styles.Muted.Render("Open issues:"))
+		for _, issue := range log.Issues {
+			if !issue.Done {
+				fmt.Println(format.FormatBullet(issue.Text))
+			}
+		}
+	}
+
+	if size > cfg.ProgressBudget() {
+		fmt.Println()
+		fmt.Println(format.FormatNextStep("ralph progress compact", "to fold old entries into patterns"))
+	}
+
+	return nil
+}
+
+// ProgressPatterns prints the Codebase Patterns section
+func ProgressPatterns() error {
+	_, log, err := loadProgress()
+	if err != nil {
+		return err
+	}
+
+	if len(log.Patterns) == 0 {
+		fmt.Println(styles.Muted.Render("No codebase patterns recorded yet."))
+		return nil
+	}
+
+	fmt.Println(format.FormatHeader(progress.PatternsTitle))
+	fmt.Println()
+	for _, pattern := range log.Patterns {
+		fmt.Println(format.FormatBullet(pattern))
+	}
+	return nil
+}
+
+// ProgressCompact folds old entries into patterns and a one-line history,
+// keeping progress.txt under the configured budget. The full file is saved
+// to the backup directory first.
+func ProgressCompact() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	projectDir, log, err := loadProgress()
+	if err != nil {
+		return err
+	}
+
+	backup, compacted, err := compactProgress(projectDir, log, cfg)
+	if err != nil {
+		return err
+	}
+
+	if compacted == 0 {
+		fmt.Println(styles.Muted.Render("Nothing to compact."))
+		return nil
+	}
+
+	fmt.Println(format.FormatSuccess(fmt.Sprintf("Compacted %d entries", compacted)))
+	fmt.Println()
+	fmt.Println(format.FormatKeyValue("Size", formatBytes(int64(len(log.String())))))
+	fmt.Println(format.FormatKeyValue("Patterns", fmt.Sprintf("%d", len(log.Patterns))))
+	fmt.Println(format.FormatKeyValue("Full history", backup))
+	return nil
+}
+
+// compactProgress backs up progress.txt, compacts it and writes it back.
+// Nothing is written when there is nothing to compact.
+func compactProgress(projectDir string, log *progress.Log, cfg *config.Config) (string, int, error) {
+	before := log.String()
+	compacted := log.Compact(cfg.ProgressBudget(), cfg.ProgressKeep())
+	if compacted == 0 && log.String() == before {
+		return "", 0, nil
+	}
+
+	backup, err := progress.Backup(projectDir)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to back up progress.txt: %w", err)
+	}
+	if err := log.Save(projectDir); err != nil {
+		return "", 0, err
+	}
+	return backup, compacted, nil
+}
+
+// autoCompactProgress compacts progress.txt when it exceeds the size budget
+func autoCompactProgress(projectDir string) {
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+
+	info, err := os.Stat(progress.Path(projectDir))
+	if err != nil || info.Size() <= int64(cfg.ProgressBudget()) {
+		return
+	}
+
+	log, err := progress.Load(projectDir)
+	if err != nil {
+		return
+	}
+
+	backup, compacted, err := compactProgress(projectDir, log, cfg)
+	if err != nil {
+		fmt.Println(format.FormatWarning(fmt.Sprintf("Failed to compact progress.txt: %v", err)))
+	} else if compacted > 0 {
+		fmt.Println(format.FormatKeyValue("Compacted progress.txt", fmt.Sprintf("%d entries (full history: %s)", compacted, backup)))
+	}
+}
+
+// formatBytes renders a byte count in a human readable unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// progressUsage lists the progress subcommands
+const progressUsage = "Usage: ralph progress [show|patterns|compact]"
+
+// Progress dispatches the progress subcommands
+func Progress(args []string) error {
+	sub := "show"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "show":
+		return ProgressShow()
+	case "patterns":
+		return ProgressPatterns()
+	case "compact":
+		return ProgressCompact()
+	default:
+		return fmt.Errorf("unknown progress command: %s\n%s", sub, progressUsage)
+	}
+}
@@ -0,0 +1,281 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/git"
+	"github.com/kento/ralph/internal/prd"
+	"github.com/kento/ralph/internal/project"
+	"github.com/kento/ralph/internal/prompt"
+	"github.com/kento/ralph/internal/ui/format"
+	"github.com/kento/ralph/internal/ui/styles"
+)
+
+// agentBinary is the agent CLI started for each iteration
+const agentBinary = "claude"
+
+// agentArgs are the arguments passed to the agent CLI for each iteration.
+// The prompt itself is piped through stdin. Partial messages let the TUI
+// show text and tool calls while they are generated.
+var agentArgs = []string{"--dangerously-skip-permissions", "-p", "--output-format", "stream-json", "--include-partial-messages"}
+
+// resumePrompt continues an interrupted session. Arguments: the reason it
+// was interrupted, the story ID and title.
+const resumePrompt = `Your previous session was interrupted: %s
+
+Continue working on %s - %s where you left off. Check what is already done (git status, the PRD, progress.txt) before redoing anything, then finish the story as instructed at the start of this session.`
+
+// storyAssignment is appended to prompts that don't name the selected story,
+// so the agent works on the story its attempts are counted against.
+// Arguments: the story ID and title.
+const storyAssignment = `
+
+## Selected Story
+
+Ralph selected %s - %s for this iteration. Work on exactly this story, even if another one has a higher priority.
+`
+
+// iterationPlan is everything needed to start one agent iteration
+type iterationPlan struct {
+	story  *prd.UserStory
+	source *prompt.Source // nil when resuming
+	prompt string
+	env    []string // Variables added on top of the current environment
+	resume string   // Agent session to resume instead of starting a new one
+}
+
+// resumePlan is an interrupted agent session to pick up in the next iteration
+type resumePlan struct {
+	storyID   string
+	sessionID string
+	reason    string
+}
+
+// planIteration picks the prompt template and renders it for a story
+func planIteration(cfg *config.Config, projectDir, workingDir string, prdData *prd.PRD, story *prd.UserStory, iteration, maxIterations int, previous *prompt.Outcome) (*iterationPlan, error) {
+	src, err := prompt.Resolve(cfg.RalphHome, projectDir, workingDir, prdData)
+	if err != nil {
+		return nil, err
+	}
+
+	branch, _ := git.CurrentBranch(workingDir)
+	text, err := src.Render(prompt.Data{
+		ProjectDir:    projectDir,
+		WorkingDir:    workingDir,
+		Branch:        branch,
+		Iteration:     iteration,
+		MaxIterations: maxIterations,
+		PRD:           prdData,
+		Story:         prompt.NewStory(story),
+		Previous:      previous,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if story != nil && !strings.Contains(text, story.ID) {
+		text = strings.TrimRight(text, "\n") + fmt.Sprintf(storyAssignment, story.ID, story.Title)
+	}
+
+	return &iterationPlan{story: story, source: src, prompt: text, env: iterationEnv(cfg, projectDir, story, iteration)}, nil
+}
+
+// planResume continues an interrupted agent session on the same story
+// instead of rendering the prompt template again
+func planResume(cfg *config.Config, projectDir string, story *prd.UserStory, iteration int, r *resumePlan) *iterationPlan {
+	return &iterationPlan{
+		story:  story,
+		prompt: fmt.Sprintf(resumePrompt, r.reason, story.ID, story.Title),
+		env:    iterationEnv(cfg, projectDir, story, iteration),
+		resume: r.sessionID,
+	}
+}
+
+// iterationEnv returns the variables that tell the agent about the iteration
+func iterationEnv(cfg *config.Config, projectDir string, story *prd.UserStory, iteration int) []string {
+	env := []string{
+		"RALPH_HOME=" + cfg.RalphHome,
+		"RALPH_PROJECT_DIR=" + projectDir,
+		fmt.Sprintf("RALPH_ITERATION=%d", iteration),
+	}
+	if story != nil {
+		env = append(env, "RALPH_STORY_ID="+story.ID)
+	}
+	return env
+}
+
+// args returns the agent arguments for this iteration
+func (ip *iterationPlan) args() []string {
+	if ip.resume != "" {
+		return append(slices.Clone(agentArgs), "--resume", ip.resume)
+	}
+	return agentArgs
+}
+
+// command builds the agent process for this iteration
+func (ip *iterationPlan) command(ctx context.Context, workingDir string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, agentBinary, ip.args()...)
+	cmd.Dir = workingDir
+	cmd.Env = append(os.Environ(), ip.env...)
+
+	// Cancelling asks the agent and its children to exit; the run follows
+	// up with SIGKILL if they don't (see stopProcessGroup)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminateProcessGroup(cmd.Process.Pid)
+	}
+	// Don't wait forever on pipes held open by leftover children
+	cmd.WaitDelay = agentStopGrace
+	return cmd
+}
+
+// commandLine renders the agent invocation as a shell command
+func commandLine(args []string) string {
+	parts := []string{agentBinary}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t\"'$") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// PromptPreview renders the prompt the agent would receive for a story
+// (the next incomplete one if storyID is empty) and prints it with its size
+func PromptPreview(storyID string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	projectDir, err := project.GetProjectDir()
+	if err != nil {
+		return err
+	}
+	workingDir, _ := os.Getwd()
+
+	var prdData *prd.PRD
+	var story *prd.UserStory
+	if prd.Exists(projectDir) {
+		if prdData, err = prd.Load(projectDir); err != nil {
+			return fmt.Errorf("failed to load prd.json: %w", err)
+		}
+		if storyID != "" {
+			if story = prdData.FindStory(storyID); story == nil {
+				return fmt.Errorf("story %s not found in prd.json", storyID)
+			}
+		} else {
+			story = prdData.NextIncomplete()
+		}
+	} else if storyID != "" {
+		return fmt.Errorf("no prd.json found. Run 'ralph prd' first")
+	}
+
+	plan, err := planIteration(cfg, projectDir, workingDir, prdData, story, 1, DefaultMaxIterations, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(plan.prompt)
+	fmt.Println(styles.Subtle.Render(strings.Repeat("─", 40)))
+	printPromptSummary(plan)
+
+	return nil
+}
+
+// printPromptSummary prints the template source, story and size of a prompt
+func printPromptSummary(plan *iterationPlan) {
+	fmt.Println(format.FormatKeyValue("Template", fmt.Sprintf("%s (%s)", plan.source.Path, plan.source.Origin)))
+	if len(plan.source.Partials) > 0 {
+		fmt.Println(format.FormatKeyValue("Partials", strings.Join(plan.source.PartialNames(), ", ")))
+	}
+	if plan.story != nil {
+		fmt.Println(format.FormatKeyValue("Story", fmt.Sprintf("[%s] %s", plan.story.ID, plan.story.Title)))
+	} else {
+		fmt.Println(format.FormatKeyValue("Story", styles.Muted.Render("none selected")))
+	}
+	lines := strings.Count(plan.prompt, "\n") + 1
+	fmt.Println(format.FormatKeyValue("Size", fmt.Sprintf("%d bytes, %d lines", len(plan.prompt), lines)))
+	fmt.Println(format.FormatKeyValue("Tokens", fmt.Sprintf("~%d (estimated)", prompt.EstimateTokens(plan.prompt))))
+}
+
+// DryRun prints the agent command, environment and prompt for each planned
+// iteration without starting any process. Each story is assumed to pass on
+// its first attempt.
+func DryRun(maxIterations int) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	projectDir, err := project.GetProjectDir()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+		return fmt.Errorf("project not initialized. Run 'ralph init' first")
+	}
+	if !prd.Exists(projectDir) {
+		return fmt.Errorf("no prd.json found. Run 'ralph prd' first")
+	}
+
+	workingDir, _ := os.Getwd()
+
+	// Work on a copy so the simulated completions never touch prd.json
+	prdData, err := prd.Load(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to load prd.json: %w", err)
+	}
+
+	fmt.Println(format.FormatHeader("Ralph Dry Run"))
+	fmt.Println()
+	fmt.Println(format.FormatKeyValue("Working dir", workingDir))
+
+	planned := 0
+	for i := 0; i < maxIterations; i++ {
+		story := prdData.NextIncomplete()
+		if story == nil {
+			break
+		}
+
+		plan, err := planIteration(cfg, projectDir, workingDir, prdData, story, i+1, maxIterations, nil)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+		fmt.Println(format.FormatSection(fmt.Sprintf("Iteration %d", i+1), 80))
+		fmt.Println()
+		fmt.Println(format.FormatKeyValue("Command", commandLine(plan.args())+" < prompt"))
+		if cfg.ResumeRetries > 0 {
+			// An interrupted session is resumed by the next iteration
+			resume := planResume(cfg, projectDir, story, i+2, &resumePlan{storyID: story.ID, sessionID: "<session-id>"})
+			fmt.Println(format.FormatKeyValue("If interrupted", commandLine(resume.args())+" < resume prompt"))
+		}
+		fmt.Println(format.FormatKeyValue("Env", styles.Muted.Render("(current environment plus)")))
+		for _, kv := range plan.env {
+			fmt.Println(format.FormatBullet(kv))
+		}
+		printPromptSummary(plan)
+		fmt.Println()
+		fmt.Println(plan.prompt)
+
+		story.Passes = true
+		planned++
+	}
+
+	fmt.Println()
+	if planned == 0 {
+		fmt.Println(styles.Muted.Render("Nothing to run: no incomplete, unblocked stories."))
+		return nil
+	}
+	fmt.Println(format.FormatSuccess(fmt.Sprintf("%d iteration(s) planned, no process started", planned)))
+	return nil
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/prd"
+)
+
+func TestPlanIterationNamesSelectedStory(t *testing.T) {
+	home, projectDir, workingDir := t.TempDir(), t.TempDir(), t.TempDir()
+	cfg := &config.Config{RalphHome: home}
+	p := &prd.PRD{UserStories: []prd.UserStory{
+		{ID: "US-001", Title: "First", Priority: 2},
+		{ID: "US-002", Title: "Second", Priority: 1},
+	}}
+	story := &p.UserStories[1]
+
+	// A prompt that lets the agent pick a story gets the selection appended
+	if err := os.WriteFile(filepath.Join(projectDir, "prompt.md"), []byte("Pick the highest priority story.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planIteration(cfg, projectDir, workingDir, p, story, 1, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plan.prompt, "Pick the highest priority story.\n\n## Selected Story") {
+		t.Errorf("prompt = %q, want the selected story section after the template", plan.prompt)
+	}
+	if !strings.Contains(plan.prompt, "US-002 - Second") {
+		t.Errorf("prompt = %q, want the selected story named", plan.prompt)
+	}
+
+	// A prompt that already names the story is left as rendered
+	if err := os.WriteFile(filepath.Join(projectDir, "prompt.md"), []byte("Work on {{.Story.ID}}."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = planIteration(cfg, projectDir, workingDir, p, story, 1, 10, nil); err != nil {
+		t.Fatal(err)
+	}
+	if plan.prompt != "Work on US-002." {
+		t.Errorf("prompt = %q, want %q", plan.prompt, "Work on US-002.")
+	}
+}
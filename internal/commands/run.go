@@ -1,15 +1,17 @@
 package commands
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
@@ -18,8 +20,12 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/kento/ralph/internal/config"
+	"github.com/kento/ralph/internal/git"
 	"github.com/kento/ralph/internal/prd"
 	"github.com/kento/ralph/internal/project"
+	"github.com/kento/ralph/internal/prompt"
+	"github.com/kento/ralph/internal/runlog"
+	"github.com/kento/ralph/internal/runstate"
 	"github.com/kento/ralph/internal/stream"
 	"github.com/kento/ralph/internal/ui/format"
 	"github.com/kento/ralph/internal/ui/styles"
@@ -68,36 +74,117 @@ var spinnerColors = []lipgloss.Color{
 	lipgloss.Color("#C4B5FD"), // Even lighter purple
 }
 
+// agentStopGrace is how long the agent gets to exit after SIGTERM before
+// its process group is killed
+const agentStopGrace = 5 * time.Second
+
+// Wait before retrying an iteration that hit a rate limit or overload,
+// doubling from throttleBackoffMin up to throttleBackoffMax. A reset time
+// announced by the API replaces the backoff, plus throttleResetMargin.
+const (
+	throttleBackoffMin  = 30 * time.Second
+	throttleBackoffMax  = 30 * time.Minute
+	throttleResetMargin = 10 * time.Second
+)
+
 // runState holds shared state between TUI and runner goroutine
 type runState struct {
 	mu         sync.Mutex
 	cancel     context.CancelFunc
 	currentCmd *exec.Cmd
+	force      chan struct{} // Closed to skip the rest of the grace period
+	forceOnce  sync.Once
 }
 
+func newRunState(cancel context.CancelFunc) *runState {
+	return &runState{cancel: cancel, force: make(chan struct{})}
+}
+
+// setCmd stores the running agent so it can be stopped. Called after Start.
 func (s *runState) setCmd(cmd *exec.Cmd) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.currentCmd = cmd
 }
 
-func (s *runState) killCurrentProcess() {
+// stopCurrentProcess cancels the run and stops the agent's process group:
+// SIGTERM first, SIGKILL if it is still running after grace. Returns once
+// the processes are gone.
+func (s *runState) stopCurrentProcess(grace time.Duration) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	if s.currentCmd != nil && s.currentCmd.Process != nil {
-		// Kill the process group to ensure all child processes are killed
-		s.currentCmd.Process.Kill()
-	}
+	cmd := s.currentCmd
 	if s.cancel != nil {
 		s.cancel()
 	}
+	s.mu.Unlock()
+
+	if cmd != nil && cmd.Process != nil {
+		stopProcessGroup(cmd.Process.Pid, grace, s.force)
+	}
+}
+
+// forceStop kills the agent without waiting for the rest of the grace period
+func (s *runState) forceStop() {
+	s.forceOnce.Do(func() { close(s.force) })
+}
+
+// stopProcessGroup sends SIGTERM to the process group led by pid and SIGKILL
+// when it outlives grace or force is closed
+func stopProcessGroup(pid int, grace time.Duration, force <-chan struct{}) {
+	if !processGroupAlive(pid) {
+		return
+	}
+	terminateProcessGroup(pid)
+
+	deadline := time.NewTimer(grace)
+	defer deadline.Stop()
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+
+	for processGroupAlive(pid) {
+		select {
+		case <-deadline.C:
+			killProcessGroup(pid)
+			return
+		case <-force:
+			killProcessGroup(pid)
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// forwardSignals makes SIGINT, SIGTERM and SIGHUP quit the TUI like "q", so
+// the run stops the agent and saves its log and state. A second signal
+// skips the grace period given to the agent. Call the returned function to
+// restore the default handling.
+func forwardSignals(p *tea.Program, state *runState) func() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	go func() {
+		first := true
+		for sig := range sigs {
+			if first {
+				p.Send(signalMsg{sig: sig})
+				first = false
+			} else {
+				state.forceStop()
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}
 }
 
 type runModel struct {
 	viewport          viewport.Model
 	progress          progress.Model
 	spinner           spinner.Model
-	content           *strings.Builder
+	content           *transcript
 	labelIndex        int
 	colorFrame        int
 	iteration         int
@@ -107,6 +194,7 @@ type runModel struct {
 	branch            string
 	completed         int
 	total             int
+	blocked           int
 	running           bool
 	done              bool
 	err               error
@@ -115,6 +203,12 @@ type runModel struct {
 	projectDir        string
 	workingDir        string
 	state             *runState
+	log               *runlog.Logger  // Written as output arrives, nil in tests
+	session           *stream.Session // Agent session of the current iteration
+	throttle          *throttleMsg    // Wait for a rate or usage limit, until the next prompt
+	todos             []stream.Todo   // Agent's todo list in the current iteration
+	rendered          string          // Transcript as last rendered into the viewport
+	stale             bool            // Streamed output not rendered yet
 	claudeLabelShown  bool
 	disableAnimations bool // For testing: disables spinner and animated label
 }
@@ -126,13 +220,44 @@ type promptMsg struct {
 	content string
 }
 type iterationCompleteMsg struct {
-	success bool
+	success   bool
+	throttled bool     // Not counted against the max, the iteration is retried
+	stderr    []string // Agent stderr, written to the log as its own section
+}
+type throttleMsg struct {
+	failure stream.Failure
+	until   time.Time
+}
+type stderrMsg struct {
+	line    string
+	failure stream.Failure
+}
+type signalMsg struct {
+	sig os.Signal
 }
 type runDoneMsg struct {
 	success bool
 	err     error
 }
 
+// InterruptedError is returned by Run when a signal stopped the run
+type InterruptedError struct {
+	Signal os.Signal
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted (%v)", e.Signal)
+}
+
+// ExitCode follows the shell convention for processes killed by a signal:
+// 128 + the signal number (130 for SIGINT, 143 for SIGTERM)
+func (e *InterruptedError) ExitCode() int {
+	if sig, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return 1
+}
+
 func (m runModel) Init() tea.Cmd {
 	return m.spinner.Tick
 }
@@ -148,90 +273,190 @@ func (m runModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		footerHeight := 8
 		m.viewport.Width = msg.Width - 4
 		m.viewport.Height = msg.Height - headerHeight - footerHeight - 4
-		m.viewport.SetContent(m.padContentToBottom(m.content.String()))
+		m.refresh()
 		return m, nil
 
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "ctrl+c":
+			// Run stops the agent once the TUI is gone
 			m.done = true
-			// Kill the running process
-			if m.state != nil {
-				m.state.killCurrentProcess()
-			}
 			return m, tea.Quit
+		case "s":
+			// Expand or collapse the work of finished sub-agents
+			m.content.expanded = !m.content.expanded
+			m.refresh()
+			return m, nil
 		}
 
+	case signalMsg:
+		m.logEvent(runlog.Event{Type: "signal", Text: msg.sig.String()})
+		m.done = true
+		m.running = false
+		m.err = &InterruptedError{Signal: msg.sig}
+		return m, tea.Quit
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
 		m.colorFrame++
+		// Streamed output and the durations of running sub-agents
+		if m.stale || m.content.agentsRunning() {
+			atBottom := m.viewport.AtBottom()
+			m.refresh()
+			if m.stale || atBottom {
+				m.viewport.GotoBottom()
+			}
+			m.stale = false
+		}
 		return m, cmd
 
 	case promptMsg:
 		// Display the prompt with "Ralph →" header
 		line := format.FormatPrompt(msg.content)
-		m.content.WriteString(line + "\n\n")
-		m.viewport.SetContent(m.padContentToBottom(m.content.String()))
+		m.appendContent(line + "\n\n")
+		m.logEvent(runlog.Event{Type: "prompt", Text: msg.content})
+		m.refresh()
 		m.viewport.GotoBottom()
 		// Reset Claude label for new prompt
 		m.claudeLabelShown = false
+		m.throttle = nil
 
 	case outputMsg:
 		result := msg.result
 
 		// Add "Claude →" header before first output
 		if !m.claudeLabelShown {
-			m.content.WriteString(format.FormatClaudeHeader() + "\n")
+			m.appendContent(format.FormatClaudeHeader() + "\n")
 			m.claudeLabelShown = true
 		}
 
 		// Rotate label on tool calls
-		if result.Type == stream.OutputToolCall {
+		if result.Type == stream.OutputToolCall && !result.Partial {
 			m.labelIndex = (m.labelIndex + 1) % len(funLabels)
 		}
 
+		// Session metadata goes to the footer and the logs, not the transcript
+		if result.Type == stream.OutputInit {
+			m.session = result.Session
+			m.logEvent(runlog.Event{Type: result.Type.String(), Text: result.Display, Data: result.Session})
+			if m.log != nil {
+				m.log.Write(styles.Muted.Render(fmt.Sprintf("Session %s · %s", result.Session.ID, result.Display)) + "\n")
+			}
+			return m, nil
+		}
+
+		// TodoWrite replaces the checklist in the footer
+		if result.Todos != nil {
+			m.todos = result.Todos
+		}
+
+		// A tool result updates the line of its call instead of adding one
+		if result.Type == stream.OutputToolResult {
+			m.finishTool(result)
+			return m, nil
+		}
+
 		// Format the output based on type
 		var line string
 		switch result.Type {
 		case stream.OutputToolCall:
-			line = format.FormatToolCall(result.ToolName, result.Context)
+			line = format.FormatToolCall(toolFormat(result))
 		case stream.OutputResult:
 			line = format.FormatDone(result.Display)
 		case stream.OutputError:
 			line = format.FormatError(result.Display)
+		case stream.OutputWarning:
+			line = format.FormatWarning(result.Display)
 		default:
 			line = result.Display
 		}
 
+		if !result.Partial {
+			m.logEvent(runlog.Event{
+				Type:    result.Type.String(),
+				Tool:    result.ToolName,
+				Context: result.Context,
+				Text:    result.Display,
+			})
+		}
+
 		if line != "" {
-			m.content.WriteString(line + "\n")
-			m.viewport.SetContent(m.padContentToBottom(m.content.String()))
-			m.viewport.GotoBottom()
+			index := m.showOutput(result, line+"\n")
+			if result.Type == stream.OutputToolCall && !result.Partial {
+				m.content.trackTool(result.ToolID, index, toolFormat(result), result.ParentID)
+			}
+			if result.Partial {
+				// Deltas arrive per token: redraw on the next spinner tick
+				// rather than render the whole transcript for each
+				m.stale = true
+			} else {
+				m.refresh()
+				m.viewport.GotoBottom()
+			}
 		}
 
+	case stderrMsg:
+		// Shown as it arrives, but logged as a section at the end of the
+		// iteration instead of interleaved with the transcript
+		line := format.FormatWarning(msg.line)
+		event := runlog.Event{Type: "stderr", Text: msg.line}
+		if msg.failure != stream.FailureNone {
+			line = format.FormatError(msg.failure.String() + ": " + msg.line)
+			event.Error = msg.failure.String()
+		}
+		m.logEvent(event)
+		m.content.append(line + "\n")
+		m.refresh()
+		m.viewport.GotoBottom()
+
+	case throttleMsg:
+		m.throttle = &msg
+		text := fmt.Sprintf("%s, retrying at %s (not counted against the iteration limit)", msg.failure, msg.until.Format("15:04:05"))
+		m.logEvent(runlog.Event{Type: "throttle", Text: text, Error: msg.failure.String(), Data: msg.until})
+		m.appendContent(format.FormatWarning(text) + "\n")
+		m.refresh()
+		m.viewport.GotoBottom()
+
 	case iterationCompleteMsg:
+		// The log keeps where the agent's todo list ended up
+		if len(m.todos) > 0 {
+			if m.log != nil {
+				lines := make([]string, len(m.todos))
+				for i, todo := range m.todos {
+					lines[i] = formatTodo(todo)
+				}
+				m.log.Write("\n" + format.FormatSection("Todos", 60) + "\n" + strings.Join(lines, "\n") + "\n")
+			}
+			m.logEvent(runlog.Event{Type: "todos", Data: m.todos})
+			m.todos = nil
+		}
+		writeStderr(m.log, msg.stderr)
+		m.logEvent(runlog.Event{Type: "iteration", Success: msg.success})
 		if msg.success {
 			m.completed++
-			// Reload PRD and update current story to show the next incomplete one
-			if prdData, err := prd.Load(m.projectDir); err == nil && prdData != nil {
-				if next := prdData.NextIncomplete(); next != nil {
-					m.currentStory = next.ID
-					m.currentStoryTitle = next.Title
-				} else {
-					// All stories complete
-					m.currentStory = ""
-					m.currentStoryTitle = ""
-				}
+		}
+		// Reload PRD and update current story to show the next incomplete one
+		if prdData, err := prd.Load(m.projectDir); err == nil && prdData != nil {
+			m.blocked = prdData.BlockedCount()
+			if next := prdData.NextIncomplete(); next != nil {
+				m.currentStory = next.ID
+				m.currentStoryTitle = next.Title
+			} else {
+				// All stories complete or blocked
+				m.currentStory = ""
+				m.currentStoryTitle = ""
 			}
 		}
-		m.iteration++
+		if !msg.throttled {
+			m.iteration++
+		}
 
 		// Add iteration separator
 		if m.iteration < m.maxIterations && m.completed < m.total {
 			separator := format.FormatSection(fmt.Sprintf("Iteration %d", m.iteration+1), m.width-4)
-			m.content.WriteString("\n" + separator + "\n\n")
-			m.viewport.SetContent(m.padContentToBottom(m.content.String()))
+			m.appendContent("\n" + separator + "\n\n")
+			m.refresh()
 			m.viewport.GotoBottom()
 		}
 
@@ -242,6 +467,11 @@ func (m runModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case runDoneMsg:
+		done := runlog.Event{Type: "done", Success: msg.success}
+		if msg.err != nil {
+			done.Error = msg.err.Error()
+		}
+		m.logEvent(done)
 		m.done = true
 		m.running = false
 		m.err = msg.err
@@ -263,16 +493,26 @@ func (m runModel) View() string {
 	var b strings.Builder
 
 	// Build viewport content with optional loading text at bottom
-	content := m.content.String()
+	content := m.rendered
 	if m.running && !m.disableAnimations {
 		label := funLabels[m.labelIndex]
 		loadingText := m.spinner.View() + " " + m.renderAnimatedLabel(label)
 		content += loadingText
 	}
 
+	// The todo checklist takes its lines from the viewport
+	todos := m.renderTodos()
+
 	// Create a temporary viewport with the content including loading text
 	tempViewport := m.viewport
 	tempViewport.SetContent(m.padContentToBottom(content))
+	if n := strings.Count(todos, "\n"); n > 0 {
+		atBottom := tempViewport.AtBottom()
+		tempViewport.Height = max(tempViewport.Height-n, 1)
+		if atBottom {
+			tempViewport.GotoBottom()
+		}
+	}
 
 	// Viewport with output (at top)
 	b.WriteString(tempViewport.View() + "\n")
@@ -300,15 +540,75 @@ func (m runModel) View() string {
 		}
 		b.WriteString(styles.Muted.Render(fmt.Sprintf("%-8s", "Story")) + storyInfo + "\n")
 	}
+	if m.blocked > 0 {
+		b.WriteString(styles.Muted.Render(fmt.Sprintf("%-8s", "Blocked")) + styles.WarningText.Render(fmt.Sprintf("%d stories", m.blocked)) + "\n")
+	}
+	if m.session != nil {
+		b.WriteString(styles.Muted.Render(fmt.Sprintf("%-8s", "Agent")) + m.session.Summary() + styles.Muted.Render(" · session "+shortSession(m.session.ID)) + "\n")
+	}
+	b.WriteString(todos)
+	if m.throttle != nil {
+		b.WriteString(styles.Muted.Render(fmt.Sprintf("%-8s", "Waiting")) + styles.WarningText.Render(fmt.Sprintf("%s, retrying at %s", m.throttle.failure, m.throttle.until.Format("15:04:05"))) + "\n")
+	}
 	b.WriteString("\n")
 
 	// Help
-	help := styles.Subtle.Render("q quit • ↑/↓ scroll")
+	keys := "q quit • ↑/↓ scroll"
+	if m.content.hasAgents() {
+		keys += " • s sub-agents"
+	}
+	help := styles.Subtle.Render(keys)
 	b.WriteString(help)
 
 	return b.String()
 }
 
+// maxTodoLines is how many todos the checklist in the footer shows
+const maxTodoLines = 6
+
+// renderTodos renders the agent's todo list for the footer. Long lists
+// show the todos around the first one not completed.
+func (m runModel) renderTodos() string {
+	if len(m.todos) == 0 {
+		return ""
+	}
+
+	done := 0
+	open := len(m.todos)
+	for i, todo := range m.todos {
+		if todo.Status == stream.TodoCompleted {
+			done++
+		} else if i < open {
+			open = i
+		}
+	}
+	start := max(min(open-1, len(m.todos)-maxTodoLines), 0)
+	end := min(start+maxTodoLines, len(m.todos))
+
+	indent := strings.Repeat(" ", 8)
+	var b strings.Builder
+	b.WriteString(styles.Muted.Render(fmt.Sprintf("%-8s", "Todos")) + fmt.Sprintf("%d/%d done", done, len(m.todos)) + "\n")
+	if start > 0 {
+		b.WriteString(indent + styles.Muted.Render(fmt.Sprintf("… %d more", start)) + "\n")
+	}
+	for _, todo := range m.todos[start:end] {
+		b.WriteString(indent + formatTodo(todo) + "\n")
+	}
+	if end < len(m.todos) {
+		b.WriteString(indent + styles.Muted.Render(fmt.Sprintf("… %d more", len(m.todos)-end)) + "\n")
+	}
+	return b.String()
+}
+
+// formatTodo renders a todo, in progress ones in their active form
+func formatTodo(todo stream.Todo) string {
+	text := todo.Content
+	if todo.Status == stream.TodoInProgress && todo.ActiveForm != "" {
+		text = todo.ActiveForm
+	}
+	return format.FormatTodo(text, todo.Status)
+}
+
 func (m runModel) renderProgressBar() string {
 	if m.total == 0 {
 		return styles.Muted.Render("Progress: No stories loaded")
@@ -369,6 +669,91 @@ func waveDistance(pos, waveHeadPos, labelLen int) int {
 	return diff
 }
 
+// shortSession abbreviates an agent session ID for the footer
+func shortSession(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// refresh renders the transcript into the viewport
+func (m *runModel) refresh() {
+	m.rendered = m.content.String()
+	m.viewport.SetContent(m.padContentToBottom(m.rendered))
+}
+
+// appendContent adds rendered output to the viewport content and the run
+// log, and returns its transcript entry
+func (m *runModel) appendContent(s string) int {
+	if m.log != nil {
+		m.log.Write(s)
+	}
+	return m.content.append(s)
+}
+
+// showOutput shows a line of agent output. Streamed output replaces what
+// was shown for its block so far; only complete output goes to the run log,
+// where sub-agent output is indented as in the viewport.
+func (m *runModel) showOutput(result stream.ParseResult, s string) int {
+	index := m.content.setBlock(result.ParentID, result.BlockID, s)
+	if !result.Partial && m.log != nil {
+		m.log.Write(indentLines(s, m.content.prefix(result.ParentID)))
+	}
+	return index
+}
+
+// finishTool shows the outcome of a tool call. The run log is append-only,
+// so it only gets the output of failed calls, below wherever the log is.
+func (m *runModel) finishTool(result stream.ParseResult) {
+	entry, found := m.content.finishTool(result.ToolID, result.IsError, result.Display)
+
+	event := runlog.Event{Type: result.Type.String(), Tool: entry.tool.Name, Success: !result.IsError}
+	if result.IsError {
+		event.Error = result.Display
+	}
+	m.logEvent(event)
+
+	if result.IsError && result.Display != "" {
+		output := format.FormatToolOutput(result.Display) + "\n"
+		if m.log != nil {
+			m.log.Write(indentLines(output, m.content.prefix(entry.parent)))
+		}
+		if !found {
+			m.content.append(output)
+		}
+	}
+
+	// The run log gets a sub-agent's summary once it is done
+	if agent, ok := m.content.agents[result.ToolID]; ok && m.log != nil {
+		m.log.Write(m.content.agentSummary(agent) + "\n")
+	}
+
+	m.refresh()
+	m.viewport.GotoBottom()
+}
+
+// toolFormat returns how a tool call is shown, by its display rule
+func toolFormat(result stream.ParseResult) format.Tool {
+	return format.Tool{
+		Name:    result.ToolName,
+		Context: result.Context,
+		Label:   result.ToolStyle.Label,
+		Color:   result.ToolStyle.Color,
+		Icon:    result.ToolStyle.Icon,
+	}
+}
+
+// logEvent adds an event for the current iteration to the structured log
+func (m *runModel) logEvent(e runlog.Event) {
+	if m.log == nil {
+		return
+	}
+	e.Iteration = m.iteration + 1
+	e.Story = m.currentStory
+	m.log.Event(e)
+}
+
 func (m runModel) padContentToBottom(content string) string {
 	lines := strings.Split(content, "\n")
 	contentHeight := len(lines)
@@ -403,9 +788,29 @@ func Run(maxIterations int) error {
 		return err
 	}
 
+	// Keep progress.txt within its size budget before the agent reads it again
+	autoCompactProgress(projectDir)
+
+	// Track iterations, cost and the starting commit for the archive metadata
+	branchName := "unknown"
+	if prdData, err := prd.Load(projectDir); err == nil {
+		if prdData.BranchName != "" {
+			branchName = prdData.BranchName
+		}
+		if err := beginRunState(projectDir, workingDir, prdData.BranchName); err != nil {
+			fmt.Println(format.FormatWarning(fmt.Sprintf("Failed to save run state: %v", err)))
+		}
+	}
+
+	// Write the log while the run happens so a crash doesn't lose it
+	logger, err := openRunLog(projectDir, branchName)
+	if err != nil {
+		fmt.Println(format.FormatWarning(fmt.Sprintf("Failed to open run log: %v", err)))
+	}
+
 	// Create context for cancellation
 	ctx, cancel := context.WithCancel(context.Background())
-	state := &runState{cancel: cancel}
+	state := newRunState(cancel)
 
 	// Initialize model
 	vp := viewport.New(80, 20)
@@ -423,12 +828,13 @@ func Run(maxIterations int) error {
 		viewport:      vp,
 		progress:      progress.New(progress.WithDefaultGradient(), progress.WithWidth(30), progress.WithoutPercentage()),
 		spinner:       s,
-		content:       &strings.Builder{},
+		content:       newTranscript(),
 		maxIterations: maxIterations,
 		projectDir:    projectDir,
 		workingDir:    workingDir,
 		running:       true,
 		state:         state,
+		log:           logger,
 	}
 
 	// Load initial PRD state (existence already validated above)
@@ -437,22 +843,35 @@ func Run(maxIterations int) error {
 		m.branch = prdData.BranchName
 		m.completed = prdData.CompletedCount()
 		m.total = prdData.TotalCount()
+		m.blocked = prdData.BlockedCount()
 		if next := prdData.NextIncomplete(); next != nil {
 			m.currentStory = next.ID
 			m.currentStoryTitle = next.Title
 		}
 	}
 
-	// Run in alternate screen
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	// Run in alternate screen. Signals are handled here rather than by
+	// Bubble Tea so that SIGHUP also saves the log and state.
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithoutSignalHandler())
+	stopSignals := forwardSignals(p, state)
+	defer stopSignals()
 
 	// Start the iteration loop in background
-	go runIterationLoop(ctx, p, state, projectDir, workingDir, maxIterations)
+	loopDone := make(chan struct{})
+	go func() {
+		defer close(loopDone)
+		runIterationLoop(ctx, p, state, logger, projectDir, workingDir, maxIterations)
+	}()
 
 	finalModel, err := p.Run()
 
-	// Ensure cleanup on exit
-	cancel()
+	// Ensure cleanup on exit: stop the agent and everything it started,
+	// and let the loop log what the agent wrote to stderr
+	state.stopCurrentProcess(agentStopGrace)
+	select {
+	case <-loopDone:
+	case <-time.After(agentStopGrace):
+	}
 
 	// Check if run completed successfully (all tasks done)
 	var runSuccess bool
@@ -460,20 +879,23 @@ func Run(maxIterations int) error {
 		runSuccess = fm.completed >= fm.total && fm.total > 0 && fm.err == nil
 	}
 
-	// Persist log before exiting
-	if m, ok := finalModel.(runModel); ok && m.content != nil {
-		logContent := m.content.String()
-		if logContent != "" {
-			branchName := m.branch
-			if branchName == "" {
-				branchName = "unknown"
-			}
-			if logErr := saveRunLog(projectDir, branchName, logContent); logErr != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save run log: %v\n", logErr)
-			}
+	// Clean shutdown: flush the log and clear the crash marker
+	if logger != nil {
+		if logErr := logger.Close(); logErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close run log: %v\n", logErr)
 		}
 	}
 
+	// A signal fails the run, so callers see it didn't finish
+	if err := runExitError(finalModel, err); err != nil {
+		return err
+	}
+
+	// Explain why the loop stopped early (blocked stories, circuit breaker, ...)
+	if fm, ok := finalModel.(runModel); ok && fm.err != nil && !runSuccess {
+		fmt.Println(format.FormatWarning(fmt.Sprintf("Run stopped: %v", fm.err)))
+	}
+
 	// Auto-archive on successful completion
 	if runSuccess {
 		fmt.Println()
@@ -485,20 +907,36 @@ func Run(maxIterations int) error {
 	return err
 }
 
-func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, projectDir, workingDir string, maxIterations int) {
-	// Get ralph home from config
-	ralphHome, err := config.GetRalphHome()
+// runExitError returns the error Run reports for the TUI's final model:
+// the TUI's own error, or the interruption when a signal stopped the run
+func runExitError(finalModel tea.Model, err error) error {
 	if err != nil {
-		p.Send(runDoneMsg{err: fmt.Errorf("failed to get ralph home: %w", err)})
-		return
+		return err
 	}
+	var interrupted *InterruptedError
+	if fm, ok := finalModel.(runModel); ok && errors.As(fm.err, &interrupted) {
+		return interrupted
+	}
+	return nil
+}
 
-	promptPath := filepath.Join(ralphHome, "prompt.md")
-	if _, err := os.Stat(promptPath); os.IsNotExist(err) {
-		p.Send(runDoneMsg{err: fmt.Errorf("prompt.md not found at %s", promptPath)})
+func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, logger *runlog.Logger, projectDir, workingDir string, maxIterations int) {
+	// Get ralph home and run settings from config
+	cfg, err := config.Load()
+	if err != nil {
+		p.Send(runDoneMsg{err: fmt.Errorf("failed to get ralph home: %w", err)})
 		return
 	}
 
+	// Consecutive iterations that completed no story (circuit breaker)
+	stalled := 0
+	// Outcome of the previous iteration, passed to the prompt template
+	var previous *prompt.Outcome
+	// Interrupted session the next iteration resumes (resume_retries)
+	var resume *resumePlan
+	// Consecutive iterations turned away by a rate limit or overload (backoff)
+	throttled := 0
+
 	for i := 0; i < maxIterations; i++ {
 		// Check if context is cancelled
 		select {
@@ -507,31 +945,48 @@ func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, proj
 		default:
 		}
 
-		// Capture completed count before iteration to detect new completions
+		// Capture completed count before iteration to detect new completions,
+		// and the story this iteration is expected to work on
 		var previousCompleted int
+		var storyID string
+		var prdData *prd.PRD
+		var story *prd.UserStory
 		if prd.Exists(projectDir) {
-			if p, _ := prd.Load(projectDir); p != nil {
-				previousCompleted = p.CompletedCount()
+			if prdData, _ = prd.Load(projectDir); prdData != nil {
+				previousCompleted = prdData.CompletedCount()
+				if story = prdData.NextIncomplete(); story != nil {
+					storyID = story.ID
+				} else if !prdData.IsComplete() {
+					p.Send(runDoneMsg{err: fmt.Errorf("all remaining stories are blocked (%d)", prdData.BlockedCount())})
+					return
+				}
 			}
 		}
 
-		// Read and substitute prompt
-		promptContent, err := os.ReadFile(promptPath)
-		if err != nil {
+		// Resume an interrupted session of the same story, or resolve and
+		// render the prompt template (PRD, project, repo or global)
+		var plan *iterationPlan
+		if resume != nil && story != nil && resume.storyID == storyID {
+			plan = planResume(cfg, projectDir, story, i+1, resume)
+			if resumes, err := recordStoryResume(projectDir, storyID); err == nil {
+				story.Resumes = resumes
+				p.Send(outputMsg{result: stream.ParseResult{
+					Display: fmt.Sprintf("Resuming session %s of %s (%d/%d)", shortSession(resume.sessionID), storyID, resumes, cfg.ResumeRetries),
+					Type:    stream.OutputWarning,
+				}})
+			}
+		} else if plan, err = planIteration(cfg, projectDir, workingDir, prdData, story, i+1, maxIterations, previous); err != nil {
 			p.Send(runDoneMsg{err: err})
 			return
 		}
-
-		prompt := string(promptContent)
-		prompt = strings.ReplaceAll(prompt, "{{PROJECT_DIR}}", projectDir)
-		prompt = strings.ReplaceAll(prompt, "{{WORKING_DIR}}", workingDir)
+		resume = nil
+		agentPrompt := plan.prompt
 
 		// Send prompt to TUI for display
-		p.Send(promptMsg{content: prompt})
+		p.Send(promptMsg{content: agentPrompt})
 
 		// Run claude with context - pipe prompt via stdin with streaming output
-		cmd := exec.CommandContext(ctx, "claude", "--dangerously-skip-permissions", "-p", "--output-format", "stream-json")
-		cmd.Dir = workingDir
+		cmd := plan.command(ctx, workingDir)
 
 		// Set up stdin pipe for the prompt
 		stdin, err := cmd.StdinPipe()
@@ -540,9 +995,6 @@ func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, proj
 			return
 		}
 
-		// Store the command so it can be killed
-		state.setCmd(cmd)
-
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
 			p.Send(runDoneMsg{err: err})
@@ -560,22 +1012,27 @@ func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, proj
 			return
 		}
 
+		// Store the command so it can be stopped
+		state.setCmd(cmd)
+
 		// Write prompt to stdin and close
 		go func() {
 			defer stdin.Close()
-			io.WriteString(stdin, prompt)
+			io.WriteString(stdin, agentPrompt)
 		}()
 
-		// Stream output
+		// Stream output, remembering the last error reported by the agent
 		var wg sync.WaitGroup
+		var stdoutSummary streamSummary
+		var stderrSummary stderrSummary
 		wg.Add(2)
 		go func() {
 			defer wg.Done()
-			streamOutput(p, stdout)
+			stdoutSummary = streamOutput(p, stdout, toolRules(cfg))
 		}()
 		go func() {
 			defer wg.Done()
-			streamOutput(p, stderr)
+			stderrSummary = streamStderr(p, stderr)
 		}()
 
 		// Wait for output streams to close
@@ -584,9 +1041,11 @@ func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, proj
 		err = cmd.Wait()
 		state.setCmd(nil)
 
-		// Check if cancelled
+		// Check if cancelled. The TUI is gone, so the agent's stderr goes
+		// to the log directly: it often tells why the run was stopped.
 		select {
 		case <-ctx.Done():
+			writeStderr(logger, stderrSummary.lines)
 			return
 		default:
 		}
@@ -601,9 +1060,92 @@ func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, proj
 			}
 		}
 
+		// Stderr only explains an agent that exited with an error before
+		// reporting a result; otherwise it's just output of the tools it ran
+		if err == nil || stdoutSummary.finished {
+			stderrSummary.clearFailure()
+		}
+
 		// Check for completion signal
 		complete := checkForCompletion(projectDir, previousCompleted)
-		p.Send(iterationCompleteMsg{success: complete})
+		previous = &prompt.Outcome{Iteration: i + 1, StoryID: storyID, Success: complete}
+		sleep := time.Duration(IterationSleepSecs) * time.Second
+		promise := checkPromise(p, projectDir, stdoutSummary)
+		failure, resetAt := iterationThrottle(stdoutSummary, stderrSummary)
+		if complete || !failure.Throttled() {
+			throttled = 0
+
+			// Count the iteration and its cost towards the run totals
+			if stateErr := recordIteration(projectDir, i+1, storyID, stdoutSummary); stateErr != nil {
+				p.Send(outputMsg{result: stream.ParseResult{
+					Display: fmt.Sprintf("Failed to update run state: %v", stateErr),
+					Type:    stream.OutputError,
+				}})
+			}
+		}
+		if complete {
+			stalled = 0
+		} else if failure.Throttled() {
+			// The API turned the iteration away: wait and retry it without
+			// counting it against the max or the story's attempts
+			throttled++
+			if throttled > cfg.ThrottleRetryLimit() {
+				p.Send(runDoneMsg{err: fmt.Errorf("%s in %d consecutive iterations, stopping", failure, throttled)})
+				return
+			}
+			sleep = throttleWait(throttled, resetAt, time.Now())
+			previous.Reason = iterationFailureReason(err, stdoutSummary.lastErr, stderrSummary)
+			if story != nil && stdoutSummary.sessionID != "" && story.Resumes < cfg.ResumeRetries {
+				resume = &resumePlan{storyID: storyID, sessionID: stdoutSummary.sessionID, reason: previous.Reason}
+			}
+			p.Send(throttleMsg{failure: failure, until: time.Now().Add(sleep)})
+		} else if promise == stream.PromiseBlocked && storyID != "" {
+			// The agent gave up on the story: block it now instead of
+			// spending the remaining attempts on it
+			stalled++
+			reason := promiseReason(stdoutSummary)
+			previous.Reason = "agent reported the story blocked: " + reason
+			if blockErr := blockStory(projectDir, storyID, reason); blockErr != nil {
+				p.Send(outputMsg{result: stream.ParseResult{
+					Display: fmt.Sprintf("Failed to block %s: %v", storyID, blockErr),
+					Type:    stream.OutputError,
+				}})
+			} else {
+				p.Send(outputMsg{result: stream.ParseResult{
+					Display: fmt.Sprintf("Agent reported story %s blocked (%s), skipping", storyID, reason),
+					Type:    stream.OutputError,
+				}})
+			}
+		} else {
+			stalled++
+			reason := iterationFailureReason(err, stdoutSummary.lastErr, stderrSummary)
+			if promise == stream.PromiseNeedsHuman {
+				reason = "agent needs a human: " + promiseReason(stdoutSummary)
+			}
+			previous.Reason = reason
+			if storyID != "" {
+				blocked, recordErr := recordStoryAttempt(projectDir, storyID, reason, cfg.StoryAttemptLimit())
+				if recordErr != nil {
+					p.Send(outputMsg{result: stream.ParseResult{
+						Display: fmt.Sprintf("Failed to record attempt for %s: %v", storyID, recordErr),
+						Type:    stream.OutputError,
+					}})
+				} else if blocked {
+					p.Send(outputMsg{result: stream.ParseResult{
+						Display: fmt.Sprintf("Story %s blocked after %d attempts, skipping", storyID, cfg.StoryAttemptLimit()),
+						Type:    stream.OutputError,
+					}})
+				} else if stdoutSummary.interrupted(err) && stdoutSummary.sessionID != "" && story.Resumes < cfg.ResumeRetries {
+					resume = &resumePlan{storyID: storyID, sessionID: stdoutSummary.sessionID, reason: reason}
+				}
+			}
+		}
+		p.Send(iterationCompleteMsg{success: complete, throttled: throttled > 0, stderr: stderrSummary.lines})
+
+		if promise == stream.PromiseNeedsHuman {
+			p.Send(runDoneMsg{err: fmt.Errorf("agent needs a human: %s", promiseReason(stdoutSummary))})
+			return
+		}
 
 		if complete {
 			// Reload PRD to check if all done
@@ -620,26 +1162,309 @@ func runIterationLoop(ctx context.Context, p *tea.Program, state *runState, proj
 			}
 		}
 
+		// Circuit breaker: stop burning iterations when nothing moves forward
+		if limit := cfg.StalledIterationLimit(); stalled >= limit {
+			p.Send(runDoneMsg{err: fmt.Errorf("no progress in %d consecutive iterations, stopping", limit)})
+			return
+		}
+
 		// Sleep with context check
 		select {
 		case <-ctx.Done():
 			return
-		case <-time.After(time.Duration(IterationSleepSecs) * time.Second):
+		case <-time.After(sleep):
+		}
+		if throttled > 0 {
+			i--
 		}
 	}
 
 	p.Send(runDoneMsg{success: false, err: fmt.Errorf("max iterations reached")})
 }
 
-func streamOutput(p *tea.Program, r io.Reader) {
-	scanner := bufio.NewScanner(r)
-	parser := stream.NewParser()
-	for scanner.Scan() {
-		result := parser.ParseLine(scanner.Text())
-		if !result.IsEmpty {
+// streamSummary is what the run loop needs to know about an output stream
+type streamSummary struct {
+	lastErr string          // Last error reported in the stream
+	costUSD float64         // Cost reported by the final result event
+	session *stream.Session // Session reported by the init event
+	started time.Time       // When the init event arrived
+
+	sessionID string // Agent session, from the init or result event
+	finished  bool   // A result event arrived
+
+	failure stream.Failure // Known failure reported by an error result
+	resetAt time.Time      // When a throttled failure's limit resets
+
+	promise       stream.Promise // Last <promise> stop signal of the agent
+	promiseReason string         // Reason given with the promise
+}
+
+// interrupted reports whether the agent stopped before finishing its turn:
+// it crashed or was killed, or reported an error instead of a result
+func (s streamSummary) interrupted(cmdErr error) bool {
+	return cmdErr != nil || !s.finished || s.lastErr != ""
+}
+
+// toolRules converts the display rules from the config for the parser
+func toolRules(cfg *config.Config) []stream.ToolRule {
+	rules := make([]stream.ToolRule, len(cfg.ToolRules))
+	for i, rule := range cfg.ToolRules {
+		rules[i] = stream.ToolRule(rule)
+	}
+	return rules
+}
+
+// streamOutput forwards parsed output to the TUI and summarizes the stream.
+// Tool calls are shown by the configured display rules.
+func streamOutput(p *tea.Program, r io.Reader, rules []stream.ToolRule) streamSummary {
+	var summary streamSummary
+	reader := stream.NewLineReader(r)
+	parser := stream.NewParser(rules...)
+	for {
+		line, err := reader.ReadLine()
+		var tooLong *stream.LineTooLongError
+		if errors.As(err, &tooLong) {
+			p.Send(outputMsg{result: stream.ParseResult{Display: fmt.Sprintf("Agent output: %v", err), Type: stream.OutputWarning}})
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				p.Send(outputMsg{result: stream.ParseResult{Display: fmt.Sprintf("Failed to read agent output: %v", err), Type: stream.OutputWarning}})
+			}
+			break
+		}
+
+		for _, result := range parser.ParseLine(line) {
+			if result.Type == stream.OutputError {
+				summary.lastErr = result.Display
+			}
+			if result.CostUSD > 0 {
+				summary.costUSD = result.CostUSD
+			}
+			if result.Session != nil {
+				summary.session = result.Session
+				summary.started = time.Now()
+				summary.sessionID = result.Session.ID
+			}
+			if result.Type == stream.OutputResult || result.Type == stream.OutputError {
+				summary.finished = true
+			}
+			if result.SessionID != "" {
+				summary.sessionID = result.SessionID
+			}
+			if result.Failure != stream.FailureNone {
+				summary.failure = result.Failure
+				summary.resetAt = result.ResetAt
+			}
+			if result.Promise != stream.PromiseNone {
+				summary.promise = result.Promise
+				summary.promiseReason = result.Reason
+			}
 			p.Send(outputMsg{result: result})
 		}
 	}
+	return summary
+}
+
+// writeStderr adds the agent's stderr of an iteration to the log as its own
+// section
+func writeStderr(log *runlog.Logger, lines []string) {
+	if len(lines) > 0 && log != nil {
+		log.Write("\n" + format.FormatSection("Agent stderr", 60) + "\n" + strings.Join(lines, "\n") + "\n")
+	}
+}
+
+// stderrSummary is what the run loop needs to know about the agent's stderr
+type stderrSummary struct {
+	lines       []string       // Non-empty lines, in order
+	failure     stream.Failure // First known failure found
+	failureLine string         // Line the failure was found in
+	resetAt     time.Time      // When a throttled failure's limit resets
+}
+
+// clearFailure forgets the failure found in stderr
+func (s *stderrSummary) clearFailure() {
+	s.failure, s.failureLine, s.resetAt = stream.FailureNone, "", time.Time{}
+}
+
+// streamStderr forwards the agent's stderr to the TUI as warnings, or
+// errors for lines that point at a known failure. Stderr is plain text,
+// not stream-json.
+func streamStderr(p *tea.Program, r io.Reader) stderrSummary {
+	var summary stderrSummary
+	reader := stream.NewLineReader(r)
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			var tooLong *stream.LineTooLongError
+			if errors.As(err, &tooLong) {
+				// Keep a trace of the skipped line, stopped iterations included
+				line := fmt.Sprintf("stderr line over %d bytes skipped (%d bytes)", tooLong.Limit, tooLong.Size)
+				summary.lines = append(summary.lines, line)
+				p.Send(stderrMsg{line: line})
+				continue
+			}
+			return summary
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		failure := stream.Classify(line)
+		if failure != stream.FailureNone && summary.failure == stream.FailureNone {
+			summary.failure = failure
+			summary.failureLine = line
+		}
+		if failure.Throttled() && summary.resetAt.IsZero() {
+			summary.resetAt = stream.ResetTime(line, time.Now())
+		}
+		summary.lines = append(summary.lines, line)
+		p.Send(stderrMsg{line: line, failure: failure})
+	}
+}
+
+// beginRunState prepares run-state.json for a run on the PRD's branch,
+// recording the commit the work starts from
+func beginRunState(projectDir, workingDir, branch string) error {
+	state, err := runstate.Load(projectDir)
+	if err != nil {
+		state = &runstate.State{}
+	}
+	head, _ := git.Head(workingDir)
+	state.Begin(branch, head)
+	return state.Save(projectDir)
+}
+
+// recordIteration adds a finished iteration, its cost and agent session to
+// run-state.json
+func recordIteration(projectDir string, iteration int, storyID string, summary streamSummary) error {
+	state, err := runstate.Load(projectDir)
+	if err != nil {
+		return err
+	}
+	state.AddIteration(summary.costUSD)
+	if s := summary.session; s != nil {
+		state.AddSession(runstate.Session{
+			Iteration: iteration,
+			Story:     storyID,
+			SessionID: s.ID,
+			Model:     s.Model,
+			StartedAt: summary.started,
+		})
+	}
+	return state.Save(projectDir)
+}
+
+// iterationFailureReason summarizes why an iteration did not complete its
+// story. A failure recognized in stderr (auth, rate limit, missing command)
+// explains more than the error it causes in the stream.
+func iterationFailureReason(cmdErr error, streamErr string, stderr stderrSummary) string {
+	if stderr.failure != stream.FailureNone {
+		return fmt.Sprintf("%s: %s", stderr.failure, stderr.failureLine)
+	}
+	if streamErr != "" {
+		return streamErr
+	}
+	if cmdErr != nil {
+		if n := len(stderr.lines); n > 0 {
+			return fmt.Sprintf("agent exited with error: %v (%s)", cmdErr, stderr.lines[n-1])
+		}
+		return fmt.Sprintf("agent exited with error: %v", cmdErr)
+	}
+	return "iteration ended without the story passing"
+}
+
+// iterationThrottle returns the rate limit, overload or usage limit that
+// turned an iteration away, reported by the result or in stderr, and when
+// it resets if announced
+func iterationThrottle(stdout streamSummary, stderr stderrSummary) (stream.Failure, time.Time) {
+	if stdout.failure.Throttled() {
+		return stdout.failure, stdout.resetAt
+	}
+	if stderr.failure.Throttled() {
+		return stderr.failure, stderr.resetAt
+	}
+	return stream.FailureNone, time.Time{}
+}
+
+// throttleWait is how long to wait before the retry of the nth consecutive
+// throttled iteration: until the announced reset, or exponential backoff
+func throttleWait(n int, resetAt, now time.Time) time.Duration {
+	if resetAt.After(now) {
+		return resetAt.Sub(now) + throttleResetMargin
+	}
+	wait := throttleBackoffMin
+	for ; n > 1 && wait < throttleBackoffMax; n-- {
+		wait *= 2
+	}
+	return min(wait, throttleBackoffMax)
+}
+
+// checkPromise returns the stop signal the agent sent in a <promise> tag,
+// warning about signals Ralph doesn't know and about a COMPLETE that
+// prd.json disagrees with
+func checkPromise(p *tea.Program, projectDir string, summary streamSummary) stream.Promise {
+	switch summary.promise {
+	case stream.PromiseNone, stream.PromiseBlocked, stream.PromiseNeedsHuman:
+	case stream.PromiseComplete:
+		if prdData, err := prd.Load(projectDir); err == nil && !prdData.IsComplete() {
+			remaining := len(prdData.UserStories) - prdData.CompletedCount()
+			p.Send(outputMsg{result: stream.ParseResult{
+				Display: fmt.Sprintf("Agent reported COMPLETE, but %d of %d stories in prd.json don't pass yet, continuing", remaining, len(prdData.UserStories)),
+				Type:    stream.OutputWarning,
+			}})
+		}
+	default:
+		p.Send(outputMsg{result: stream.ParseResult{
+			Display: fmt.Sprintf("Ignoring unknown promise %q from the agent", summary.promise),
+			Type:    stream.OutputWarning,
+		}})
+		return stream.PromiseNone
+	}
+	return summary.promise
+}
+
+// promiseReason returns the reason given with the agent's promise
+func promiseReason(summary streamSummary) string {
+	if summary.promiseReason == "" {
+		return "no reason given"
+	}
+	return summary.promiseReason
+}
+
+// blockStory marks a story the agent reported as blocked in prd.json
+func blockStory(projectDir, storyID, reason string) error {
+	prdData, err := prd.Load(projectDir)
+	if err != nil {
+		return err
+	}
+	prdData.Block(storyID, reason)
+	return prdData.Save(projectDir)
+}
+
+// recordStoryResume counts a resumed session for a story in the PRD
+func recordStoryResume(projectDir, storyID string) (int, error) {
+	prdData, err := prd.Load(projectDir)
+	if err != nil {
+		return 0, err
+	}
+	resumes := prdData.RecordResume(storyID)
+	return resumes, prdData.Save(projectDir)
+}
+
+// recordStoryAttempt records a failed attempt in prd.json and reports whether
+// the story is now blocked
+func recordStoryAttempt(projectDir, storyID, reason string, maxAttempts int) (bool, error) {
+	prdData, err := prd.Load(projectDir)
+	if err != nil {
+		return false, err
+	}
+	blocked := prdData.RecordAttempt(storyID, reason, maxAttempts)
+	if err := prdData.Save(projectDir); err != nil {
+		return false, err
+	}
+	return blocked, nil
 }
 
 func checkForCompletion(projectDir string, previousCompleted int) bool {
@@ -684,7 +1509,7 @@ func NewRunModelForTest(opts TestRunOptions) tea.Model {
 		viewport:          vp,
 		progress:          progress.New(progress.WithDefaultGradient(), progress.WithWidth(30), progress.WithoutPercentage()),
 		spinner:           s,
-		content:           &strings.Builder{},
+		content:           newTranscript(),
 		iteration:         opts.Iteration,
 		maxIterations:     opts.MaxIterations,
 		currentStory:      opts.CurrentStory,
@@ -698,17 +1523,31 @@ func NewRunModelForTest(opts TestRunOptions) tea.Model {
 	}
 }
 
-func saveRunLog(projectDir, branchName, content string) error {
-	// Format: feature-name_2026-01-11-15-04-05.log
-	date := time.Now().Format("2006-01-02-15-04-05")
-	logPath := filepath.Join(projectDir, "logs", fmt.Sprintf("%s_%s.log", branchName, date))
+// openRunLog starts the run's logs (feature-name_2026-01-11-15-04-05.log and
+// .jsonl) and records them in run-state.json. Logs of a previous run that
+// crashed are marked as such first.
+func openRunLog(projectDir, branchName string) (*runlog.Logger, error) {
+	logsDir := filepath.Join(projectDir, "logs")
 
-	// Create all parent directories (handles branch names with slashes like "ralph/feature")
-	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
-		return err
+	notice := format.FormatError("Run ended without a clean shutdown (crash, kill or reboot)")
+	if crashed, err := runlog.RecoverCrash(logsDir, notice); err == nil && crashed != nil {
+		fmt.Println(format.FormatWarning(fmt.Sprintf("The previous run (pid %d) did not shut down cleanly: %s", crashed.PID, crashed.Log)))
+	}
+
+	logger, err := runlog.Open(logsDir, branchName, time.Now())
+	if err != nil {
+		return nil, err
 	}
 
-	return os.WriteFile(logPath, []byte(content), 0644)
+	if state, err := runstate.Load(projectDir); err == nil {
+		for _, path := range []string{logger.LogPath, logger.EventsPath} {
+			if rel, err := filepath.Rel(projectDir, path); err == nil {
+				state.AddLog(rel)
+			}
+		}
+		state.Save(projectDir)
+	}
+	return logger, nil
 }
 
 func checkAndArchiveOnBranchChange(projectDir string) error {
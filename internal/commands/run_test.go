@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleWait(t *testing.T) {
+	now := time.Date(2025, 6, 1, 16, 0, 0, 0, time.UTC)
+	tests := []struct {
+		n       int
+		resetAt time.Time
+		want    time.Duration
+	}{
+		{1, time.Time{}, 30 * time.Second},
+		{2, time.Time{}, time.Minute},
+		{4, time.Time{}, 4 * time.Minute},
+		{7, time.Time{}, 30 * time.Minute},
+		{50, time.Time{}, 30 * time.Minute},
+		{1, now.Add(2 * time.Hour), 2*time.Hour + throttleResetMargin},
+		{5, now.Add(time.Minute), time.Minute + throttleResetMargin},
+		// A reset time already past falls back to the backoff
+		{2, now.Add(-time.Minute), time.Minute},
+	}
+	for _, tt := range tests {
+		if got := throttleWait(tt.n, tt.resetAt, now); got != tt.want {
+			t.Errorf("throttleWait(%d, %v) = %v, want %v", tt.n, tt.resetAt, got, tt.want)
+		}
+	}
+}
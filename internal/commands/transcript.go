@@ -0,0 +1,228 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kento/ralph/internal/ui/format"
+	"github.com/kento/ralph/internal/ui/styles"
+)
+
+// transcript is the run output shown in the viewport. It is kept as a list
+// of entries so the line of a tool call can be updated once its result
+// arrives, and streamed output as more of it arrives. Output of sub-agents
+// is shown indented under the Task call that started them.
+type transcript struct {
+	entries  []transcriptEntry
+	tools    map[string]toolEntry // Tool calls by tool_use ID
+	blocks   map[string]int       // Entries of streamed content blocks by BlockID
+	agents   map[string]*subAgent // Sub-agents by the tool_use ID of their Task call
+	expanded bool                 // Show the work of finished sub-agents, not only their summary
+	now      func() time.Time
+}
+
+// transcriptEntry is rendered output, and the Task call of the sub-agent
+// that produced it
+type transcriptEntry struct {
+	text   string
+	parent string
+}
+
+// toolEntry is a tool call shown in the transcript
+type toolEntry struct {
+	index   int
+	tool    format.Tool
+	parent  string
+	started time.Time
+}
+
+// subAgent is the work of a sub-agent, collected under its Task call
+type subAgent struct {
+	index    int    // Entry of the Task call
+	outer    string // Prefix of the Task call's lines
+	prefix   string // Prefix of the sub-agent's lines
+	children []int
+	tools    int
+	started  time.Time
+	finished time.Time // Zero while running
+}
+
+// treeGuide renders the guide drawn in front of sub-agent output: "│" for
+// its lines, "└" for its summary
+func treeGuide(mark string) string {
+	return "  " + styles.Subtle.Render(mark) + " "
+}
+
+func newTranscript() *transcript {
+	return &transcript{
+		tools:  make(map[string]toolEntry),
+		blocks: make(map[string]int),
+		agents: make(map[string]*subAgent),
+		now:    time.Now,
+	}
+}
+
+// append adds rendered output and returns its entry index
+func (t *transcript) append(s string) int {
+	t.entries = append(t.entries, transcriptEntry{text: s})
+	return len(t.entries) - 1
+}
+
+// setBlock shows the output of a content block, replacing what was shown
+// for it so far. Without a block ID it appends. Output of a sub-agent goes
+// under the Task call parent.
+func (t *transcript) setBlock(parent, blockID, s string) int {
+	if index, ok := t.blocks[blockID]; ok {
+		t.entries[index].text = s
+		return index
+	}
+	index := t.append(s)
+	if agent := t.agent(parent); agent != nil {
+		t.entries[index].parent = parent
+		agent.children = append(agent.children, index)
+	}
+	if blockID != "" {
+		t.blocks[blockID] = index
+	}
+	return index
+}
+
+// agent returns the sub-agent started by a Task call, creating it on its
+// first output. Returns nil when the call isn't in the transcript.
+func (t *transcript) agent(parent string) *subAgent {
+	if parent == "" {
+		return nil
+	}
+	if agent, ok := t.agents[parent]; ok {
+		return agent
+	}
+	call, ok := t.tools[parent]
+	if !ok {
+		return nil
+	}
+	outer := t.prefix(call.parent)
+	agent := &subAgent{index: call.index, outer: outer, prefix: outer + treeGuide("│"), started: call.started}
+	t.agents[parent] = agent
+	return agent
+}
+
+// prefix returns what goes in front of the lines of a sub-agent's output
+func (t *transcript) prefix(parent string) string {
+	if agent, ok := t.agents[parent]; ok {
+		return agent.prefix
+	}
+	return ""
+}
+
+// trackTool remembers the entry of a tool call so finishTool can update it
+func (t *transcript) trackTool(id string, index int, tool format.Tool, parent string) {
+	if id != "" {
+		t.tools[id] = toolEntry{index: index, tool: tool, parent: parent, started: t.now()}
+	}
+	if agent := t.agent(parent); agent != nil {
+		agent.tools++
+	}
+}
+
+// finishTool replaces a tool call's pending icon with its outcome and, for
+// failed calls, adds the output snippet below it. Returns false when the
+// call isn't in the transcript.
+func (t *transcript) finishTool(id string, failed bool, output string) (toolEntry, bool) {
+	entry, ok := t.tools[id]
+	if !ok {
+		return toolEntry{}, false
+	}
+	delete(t.tools, id)
+	if agent, ok := t.agents[id]; ok {
+		agent.finished = t.now()
+	}
+
+	line := format.FormatToolResult(entry.tool, failed) + "\n"
+	if failed && output != "" {
+		line += format.FormatToolOutput(output) + "\n"
+	}
+	t.entries[entry.index].text = line
+	return entry, true
+}
+
+// hasAgents reports whether any sub-agent has produced output
+func (t *transcript) hasAgents() bool {
+	return len(t.agents) > 0
+}
+
+// agentsRunning reports whether a sub-agent is still at work, so the
+// duration in its summary keeps changing
+func (t *transcript) agentsRunning() bool {
+	for _, agent := range t.agents {
+		if agent.finished.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// agentSummary renders the line closing a sub-agent: its tool calls and
+// how long it ran, or has been running
+func (t *transcript) agentSummary(agent *subAgent) string {
+	end := agent.finished
+	if end.IsZero() {
+		end = t.now()
+	}
+	tools := fmt.Sprintf("%d tools", agent.tools)
+	if agent.tools == 1 {
+		tools = "1 tool"
+	}
+	summary := fmt.Sprintf("%s · %.1fs", tools, end.Sub(agent.started).Seconds())
+	return agent.outer + treeGuide("└") + styles.Muted.Render(summary)
+}
+
+func (t *transcript) String() string {
+	byIndex := make(map[int]*subAgent, len(t.agents))
+	for _, agent := range t.agents {
+		byIndex[agent.index] = agent
+	}
+
+	var b strings.Builder
+	for i, entry := range t.entries {
+		if entry.parent == "" {
+			t.render(&b, i, byIndex)
+		}
+	}
+	return b.String()
+}
+
+// render writes an entry and, for a Task call, the work of its sub-agent:
+// all of it while it runs or when expanded, else just its summary
+func (t *transcript) render(b *strings.Builder, i int, byIndex map[int]*subAgent) {
+	entry := t.entries[i]
+	b.WriteString(indentLines(entry.text, t.prefix(entry.parent)))
+
+	agent, ok := byIndex[i]
+	if !ok {
+		return
+	}
+	summary := t.agentSummary(agent)
+	if agent.finished.IsZero() || t.expanded {
+		for _, child := range agent.children {
+			t.render(b, child, byIndex)
+		}
+	} else if len(agent.children) > 0 {
+		summary += styles.Subtle.Render(" (collapsed)")
+	}
+	b.WriteString(summary + "\n")
+}
+
+// indentLines puts prefix in front of every line of s
+func indentLines(s, prefix string) string {
+	if prefix == "" {
+		return s
+	}
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
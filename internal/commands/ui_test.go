@@ -2,14 +2,19 @@ package commands
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/kento/ralph/internal/stream"
 	"github.com/muesli/termenv"
 )
 
@@ -168,6 +173,153 @@ func TestRunUINoStories(t *testing.T) {
 	tm.Quit()
 }
 
+func TestRunToolResults(t *testing.T) {
+	setupTestEnv(t)
+
+	var m tea.Model = NewRunModelForTest(TestRunOptions{DisableAnimations: true, Total: 1, Running: true, Width: 100, Height: 40})
+	parser := stream.NewParser()
+	for _, line := range []string{
+		`{"type":"assistant","message":{"content":[` +
+			`{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/src/main.go"}},` +
+			`{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","is_error":true,` +
+			`"content":[{"type":"text","text":"Exit code 1\n--- FAIL: TestParse"}]}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"package main"}]}}`,
+	} {
+		for _, result := range parser.ParseLine(line) {
+			m, _ = m.Update(outputMsg{result: result})
+		}
+	}
+
+	content := m.(runModel).content.String()
+	for _, want := range []string{"✓ Read main.go\n× Bash go test ./...\n", "⎿ Exit code 1", "⎿ --- FAIL: TestParse"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("transcript missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "package main") {
+		t.Errorf("output of a successful tool call shown:\n%s", content)
+	}
+}
+
+func TestRunSubAgents(t *testing.T) {
+	setupTestEnv(t)
+
+	var m tea.Model = NewRunModelForTest(TestRunOptions{DisableAnimations: true, Total: 1, Running: true, Width: 100, Height: 40})
+	clock := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.(runModel).content.now = func() time.Time { return clock }
+
+	parser := stream.NewParser()
+	send := func(line string) {
+		for _, result := range parser.ParseLine(line) {
+			m, _ = m.Update(outputMsg{result: result})
+		}
+		clock = clock.Add(2 * time.Second)
+	}
+	send(`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"task1","name":"Task","input":{"description":"Find the parser"}}]}}`)
+	send(`{"type":"assistant","parent_tool_use_id":"task1","message":{"content":[{"type":"tool_use","id":"s1","name":"Grep","input":{"pattern":"ParseLine"}}]}}`)
+	send(`{"type":"user","parent_tool_use_id":"task1","message":{"content":[{"type":"tool_result","tool_use_id":"s1","content":"parser.go"}]}}`)
+	send(`{"type":"assistant","parent_tool_use_id":"task1","message":{"content":[{"type":"text","text":"It is in parser.go"}]}}`)
+	send(`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/src/main.go"}}]}}`)
+
+	// Sub-agent work stays under its Task call while it runs
+	content := m.(runModel).content.String()
+	want := "● Task Find the parser\n  │ ✓ Grep ParseLine\n  │ It is in parser.go\n  └ 1 tool · 10.0s\n● Read main.go\n"
+	if !strings.Contains(content, want) {
+		t.Errorf("running sub-agent:\n%s\nwant:\n%s", content, want)
+	}
+
+	// and collapses into its summary once done, until expanded with s
+	send(`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"task1","content":"Found it"}]}}`)
+	content = m.(runModel).content.String()
+	want = "✓ Task Find the parser\n  └ 1 tool · 10.0s (collapsed)\n● Read main.go\n"
+	if !strings.Contains(content, want) {
+		t.Errorf("finished sub-agent:\n%s\nwant:\n%s", content, want)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if content = m.(runModel).content.String(); !strings.Contains(content, "  │ ✓ Grep ParseLine\n") {
+		t.Errorf("expanded sub-agent missing its tool calls:\n%s", content)
+	}
+}
+
+func TestRunTodos(t *testing.T) {
+	setupTestEnv(t)
+
+	var m tea.Model = NewRunModelForTest(TestRunOptions{DisableAnimations: true, Total: 1, Running: true, Width: 100, Height: 40})
+	todos := `[{"content":"Write tests","status":"completed"},{"content":"Add parser","status":"in_progress","activeForm":"Adding parser"}`
+	for i := 1; i <= 6; i++ {
+		todos += fmt.Sprintf(`,{"content":"Step %d","status":"pending"}`, i)
+	}
+	line := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"TodoWrite","input":{"todos":` + todos + `]}}]}}`
+	for _, result := range stream.NewParser().ParseLine(line) {
+		m, _ = m.Update(outputMsg{result: result})
+	}
+
+	if content := m.(runModel).content.String(); !strings.Contains(content, "TodoWrite 1/8 done") {
+		t.Errorf("TodoWrite call without its progress:\n%s", content)
+	}
+	panel := m.(runModel).renderTodos()
+	want := "Todos   1/8 done\n        ✓ Write tests\n        ◐ Adding parser\n        ○ Step 1\n"
+	if !strings.HasPrefix(panel, want) || !strings.HasSuffix(panel, "○ Step 4\n        … 2 more\n") {
+		t.Errorf("checklist:\n%s", panel)
+	}
+
+	// Each iteration starts with an empty list
+	m, _ = m.Update(iterationCompleteMsg{})
+	if panel := m.(runModel).renderTodos(); panel != "" {
+		t.Errorf("checklist after the iteration:\n%s", panel)
+	}
+}
+
+func TestRunStreamedOutputRedrawsOnTick(t *testing.T) {
+	setupTestEnv(t)
+
+	var m tea.Model = NewRunModelForTest(TestRunOptions{DisableAnimations: true, Total: 1, Running: true, Width: 100, Height: 40})
+	parser := stream.NewParser()
+	for _, line := range []string{
+		`{"type":"stream_event","event":{"type":"message_start","message":{"id":"msg_1"}}}`,
+		`{"type":"stream_event","event":{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}}`,
+		`{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me check"}}}`,
+	} {
+		for _, result := range parser.ParseLine(line) {
+			m, _ = m.Update(outputMsg{result: result})
+		}
+	}
+
+	// Deltas are rendered on the next spinner tick, not one by one
+	if rendered := m.(runModel).rendered; strings.Contains(rendered, "Let me check") {
+		t.Errorf("streamed output rendered before the tick:\n%s", rendered)
+	}
+	m, _ = m.Update(spinner.TickMsg{})
+	if rendered := m.(runModel).rendered; !strings.Contains(rendered, "Let me check") {
+		t.Errorf("streamed output not rendered on the tick:\n%s", rendered)
+	}
+	if m.(runModel).stale {
+		t.Error("transcript still stale after the tick")
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	setupTestEnv(t)
+
+	var m tea.Model = NewRunModelForTest(TestRunOptions{DisableAnimations: true, Total: 2, Running: true, Width: 100, Height: 40})
+	if err := runExitError(m, nil); err != nil {
+		t.Fatalf("error before the signal: %v", err)
+	}
+
+	m, cmd := m.Update(signalMsg{sig: syscall.SIGTERM})
+	if cmd == nil {
+		t.Error("signal did not quit the TUI")
+	}
+	var interrupted *InterruptedError
+	if err := runExitError(m, nil); !errors.As(err, &interrupted) {
+		t.Fatalf("error = %v, want an interruption", err)
+	}
+	if code := interrupted.ExitCode(); code != 143 {
+		t.Errorf("exit code = %d, want 143", code)
+	}
+}
+
 // readOutput drains the test model output and returns it as bytes.
 // Filters out ANSI escape sequences that might slip through.
 func readOutput(t *testing.T, tm *teatest.TestModel) []byte {
@@ -6,10 +6,105 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
+)
+
+// Defaults for optional run settings
+const (
+	DefaultMaxStoryAttempts     = 3
+	DefaultMaxStalledIterations = 5
+	DefaultMaxThrottleRetries   = 10
+	DefaultProgressMaxBytes     = 24 * 1024
+	DefaultProgressKeepEntries  = 5
+)
+
+// Archive formats
+const (
+	ArchiveFormatDir   = "dir"
+	ArchiveFormatTarGz = "tar.gz"
 )
 
 type Config struct {
 	RalphHome string `json:"ralph_home"`
+
+	// MaxStoryAttempts is how many failed iterations a story gets before it
+	// is marked as blocked and skipped. 0 uses the default.
+	MaxStoryAttempts int `json:"max_story_attempts,omitempty"`
+
+	// MaxStalledIterations stops the run after this many consecutive
+	// iterations without progress (circuit breaker). 0 uses the default.
+	MaxStalledIterations int `json:"max_stalled_iterations,omitempty"`
+
+	// MaxThrottleRetries is how many times in a row an iteration turned
+	// away by a rate limit, overload or usage limit is retried before the
+	// run stops. 0 uses the default.
+	MaxThrottleRetries int `json:"max_throttle_retries,omitempty"`
+
+	// ProgressMaxBytes is the size budget for progress.txt before old
+	// entries get compacted. 0 uses the default.
+	ProgressMaxBytes int `json:"progress_max_bytes,omitempty"`
+
+	// ProgressKeepEntries is how many recent entries compaction keeps
+	// verbatim. 0 uses the default.
+	ProgressKeepEntries int `json:"progress_keep_entries,omitempty"`
+
+	// ArchiveFormat is how new archives are stored: "dir" (default) or
+	// "tar.gz"
+	ArchiveFormat string `json:"archive_format,omitempty"`
+
+	// ArchiveKeep keeps the newest N archives per project when running
+	// `ralph gc`. 0 disables the rule.
+	ArchiveKeep int `json:"archive_keep,omitempty"`
+
+	// ArchiveMaxAgeDays keeps archives and logs younger than this many days
+	// when running `ralph gc`. 0 disables the rule.
+	ArchiveMaxAgeDays int `json:"archive_max_age_days,omitempty"`
+
+	// ResumeRetries is how many times per story an interrupted iteration
+	// (agent crash, timeout, API error) is retried by resuming its agent
+	// session before starting fresh. 0 disables resuming.
+	ResumeRetries int `json:"resume_retries,omitempty"`
+
+	// ToolRules tell how tool calls are shown in the run output: which
+	// input fields, truncated how far, and the name's label, color and
+	// icon. They go before the built-in rules, so they can override them.
+	ToolRules []ToolRule `json:"tool_rules,omitempty"`
+}
+
+// ToolRule is a tool display rule as written in the config. It has the
+// fields of stream.ToolRule, which the run converts it to.
+type ToolRule struct {
+	Match    string   `json:"match"`              // Tool name or glob, e.g. "mcp__github__*"
+	Fields   []string `json:"fields,omitempty"`   // Input fields shown, joined; none picks a common one
+	Max      int      `json:"max,omitempty"`      // Length the context is truncated to (default 40)
+	Basename bool     `json:"basename,omitempty"` // Show only the last element of paths
+	Label    string   `json:"label,omitempty"`    // Shown instead of the tool name
+	Color    string   `json:"color,omitempty"`    // Name color, hex ("#38BDF8") or ANSI ("39")
+	Icon     string   `json:"icon,omitempty"`     // Shown before the name
+}
+
+// StoryAttemptLimit returns the configured attempts per story, or the default
+func (c *Config) StoryAttemptLimit() int {
+	if c.MaxStoryAttempts > 0 {
+		return c.MaxStoryAttempts
+	}
+	return DefaultMaxStoryAttempts
+}
+
+// StalledIterationLimit returns the configured circuit breaker threshold, or the default
+func (c *Config) StalledIterationLimit() int {
+	if c.MaxStalledIterations > 0 {
+		return c.MaxStalledIterations
+	}
+	return DefaultMaxStalledIterations
+}
+
+// ThrottleRetryLimit returns the configured retries of throttled iterations, or the default
+func (c *Config) ThrottleRetryLimit() int {
+	if c.MaxThrottleRetries > 0 {
+		return c.MaxThrottleRetries
+	}
+	return DefaultMaxThrottleRetries
 }
 
 // GetClaudeConfigDir returns the Claude config directory path
@@ -50,6 +145,35 @@ func GetClaudeSkillsDir() (string, error) {
 	return skillsDir, nil
 }
 
+// ProgressBudget returns the progress.txt size budget in bytes, or the default
+func (c *Config) ProgressBudget() int {
+	if c.ProgressMaxBytes > 0 {
+		return c.ProgressMaxBytes
+	}
+	return DefaultProgressMaxBytes
+}
+
+// ProgressKeep returns how many recent progress entries to keep, or the default
+func (c *Config) ProgressKeep() int {
+	if c.ProgressKeepEntries > 0 {
+		return c.ProgressKeepEntries
+	}
+	return DefaultProgressKeepEntries
+}
+
+// CompressArchives reports whether new archives are stored as tar.gz
+func (c *Config) CompressArchives() bool {
+	return c.ArchiveFormat == ArchiveFormatTarGz
+}
+
+// ArchiveMaxAge returns the retention age for archives and logs, or 0
+func (c *Config) ArchiveMaxAge() time.Duration {
+	if c.ArchiveMaxAgeDays > 0 {
+		return time.Duration(c.ArchiveMaxAgeDays) * 24 * time.Hour
+	}
+	return 0
+}
+
 func configPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,80 @@
+package git
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// run executes a git command in dir and returns its trimmed output
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CurrentBranch returns the name of the branch checked out in dir
+func CurrentBranch(dir string) (string, error) {
+	return run(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// Head returns the commit hash checked out in dir
+func Head(dir string) (string, error) {
+	return run(dir, "rev-parse", "HEAD")
+}
+
+// Commit is a single commit of a range
+type Commit struct {
+	Hash    string
+	Subject string
+}
+
+// RevParse resolves a ref to its commit hash
+func RevParse(dir, ref string) (string, error) {
+	return run(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+}
+
+// MergeBase returns the best common ancestor of two refs
+func MergeBase(dir, a, b string) (string, error) {
+	return run(dir, "merge-base", a, b)
+}
+
+// DefaultBranch returns the branch features are based on: origin's HEAD if
+// known, otherwise main or master
+func DefaultBranch(dir string) (string, error) {
+	if ref, err := run(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return ref, nil
+	}
+	for _, branch := range []string{"main", "master"} {
+		if _, err := RevParse(dir, branch); err == nil {
+			return branch, nil
+		}
+	}
+	return "", errors.New("no default branch found")
+}
+
+// Log returns the commits in from..to, oldest first
+func Log(dir, from, to string) ([]Commit, error) {
+	out, err := run(dir, "log", "--reverse", "--format=%H%x09%s", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, line := range strings.Split(out, "\n") {
+		hash, subject, ok := strings.Cut(line, "\t")
+		if ok {
+			commits = append(commits, Commit{Hash: hash, Subject: subject})
+		}
+	}
+	return commits, nil
+}
+
+// DiffStat returns the summary line of `git diff --shortstat from..to`
+func DiffStat(dir, from, to string) (string, error) {
+	return run(dir, "diff", "--shortstat", from+".."+to)
+}
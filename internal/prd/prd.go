@@ -2,8 +2,12 @@ package prd
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 type UserStory struct {
@@ -14,13 +18,18 @@ type UserStory struct {
 	Priority           int      `json:"priority"`
 	Passes             bool     `json:"passes"`
 	Notes              string   `json:"notes"`
+	Attempts           int      `json:"attempts,omitempty"`
+	Resumes            int      `json:"resumes,omitempty"` // Interrupted sessions resumed for this story
+	Blocked            bool     `json:"blocked,omitempty"`
 }
 
 type PRD struct {
-	Project     string      `json:"project"`
-	BranchName  string      `json:"branchName"`
-	Description string      `json:"description"`
-	UserStories []UserStory `json:"userStories"`
+	Project        string      `json:"project"`
+	BranchName     string      `json:"branchName"`
+	Description    string      `json:"description"`
+	Prompt         string      `json:"prompt,omitempty"`         // Optional prompt template, relative to the project dir
+	BrowserTesting bool        `json:"browserTesting,omitempty"` // Include browser testing in the default prompt
+	UserStories    []UserStory `json:"userStories"`
 }
 
 // Load reads the prd.json file from a project directory
@@ -39,6 +48,17 @@ func Load(projectDir string) (*PRD, error) {
 	return &prd, nil
 }
 
+// Save writes the PRD back to prd.json in a project directory
+func (p *PRD) Save(projectDir string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(projectDir, "prd.json")
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
 // Exists checks if prd.json exists in a project directory
 func Exists(projectDir string) bool {
 	path := filepath.Join(projectDir, "prd.json")
@@ -57,21 +77,109 @@ func (p *PRD) CompletedCount() int {
 	return count
 }
 
+// BlockedCount returns the number of incomplete stories marked as blocked
+func (p *PRD) BlockedCount() int {
+	count := 0
+	for _, story := range p.UserStories {
+		if story.Blocked && !story.Passes {
+			count++
+		}
+	}
+	return count
+}
+
 // TotalCount returns the total number of user stories
 func (p *PRD) TotalCount() int {
 	return len(p.UserStories)
 }
 
-// NextIncomplete returns the next incomplete user story
+// NextIncomplete returns the highest priority incomplete user story that is
+// not blocked. Stories without a priority come after prioritized ones, and
+// stories with equal priority keep their document order.
 func (p *PRD) NextIncomplete() *UserStory {
+	var next *UserStory
 	for i := range p.UserStories {
-		if !p.UserStories[i].Passes {
+		story := &p.UserStories[i]
+		if story.Passes || story.Blocked {
+			continue
+		}
+		if next == nil || sortPriority(story.Priority) < sortPriority(next.Priority) {
+			next = story
+		}
+	}
+	return next
+}
+
+// sortPriority orders an unset priority (0) after every explicit one
+func sortPriority(priority int) int {
+	if priority == 0 {
+		return math.MaxInt
+	}
+	return priority
+}
+
+// FindStory returns the story with the given ID, or nil if none matches
+func (p *PRD) FindStory(id string) *UserStory {
+	for i := range p.UserStories {
+		if p.UserStories[i].ID == id {
 			return &p.UserStories[i]
 		}
 	}
 	return nil
 }
 
+// RecordAttempt counts a failed attempt at a story and appends the reason to
+// its notes. Once the story reaches maxAttempts it is marked as blocked.
+// Returns true if the story became blocked by this attempt.
+func (p *PRD) RecordAttempt(storyID, reason string, maxAttempts int) bool {
+	story := p.FindStory(storyID)
+	if story == nil || story.Passes {
+		return false
+	}
+
+	story.Attempts++
+	story.addNote(fmt.Sprintf("Attempt %d (%s): %s", story.Attempts, time.Now().Format("2006-01-02 15:04"), reason))
+
+	if maxAttempts > 0 && story.Attempts >= maxAttempts && !story.Blocked {
+		story.Blocked = true
+		return true
+	}
+	return false
+}
+
+// Block marks a story as blocked on the agent's word and appends the reason
+// to its notes. Returns true if the story became blocked.
+func (p *PRD) Block(storyID, reason string) bool {
+	story := p.FindStory(storyID)
+	if story == nil || story.Passes || story.Blocked {
+		return false
+	}
+
+	story.Blocked = true
+	story.addNote(fmt.Sprintf("Blocked by agent (%s): %s", time.Now().Format("2006-01-02 15:04"), reason))
+	return true
+}
+
+// addNote appends a line to the story's notes
+func (s *UserStory) addNote(note string) {
+	if s.Notes == "" {
+		s.Notes = note
+	} else {
+		s.Notes = strings.TrimRight(s.Notes, "\n") + "\n" + note
+	}
+}
+
+// RecordResume counts a resumed agent session for a story and returns the
+// new count
+func (p *PRD) RecordResume(storyID string) int {
+	story := p.FindStory(storyID)
+	if story == nil {
+		return 0
+	}
+	story.Resumes++
+	return story.Resumes
+}
+
 // IsComplete returns true if all user stories pass
 func (p *PRD) IsComplete() bool {
 	for _, story := range p.UserStories {
@@ -0,0 +1,98 @@
+package prd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordAttemptBlocksAfterLimit(t *testing.T) {
+	p := &PRD{UserStories: []UserStory{
+		{ID: "US-001", Title: "First"},
+		{ID: "US-002", Title: "Second"},
+	}}
+
+	if blocked := p.RecordAttempt("US-001", "tests failed", 2); blocked {
+		t.Fatal("story blocked after first attempt")
+	}
+	if blocked := p.RecordAttempt("US-001", "typecheck failed", 2); !blocked {
+		t.Fatal("story not blocked after reaching the attempt limit")
+	}
+
+	story := p.FindStory("US-001")
+	if story.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", story.Attempts)
+	}
+	if !strings.Contains(story.Notes, "Attempt 1") || !strings.Contains(story.Notes, "typecheck failed") {
+		t.Errorf("notes missing attempt reasons: %q", story.Notes)
+	}
+
+	// Blocked stories are skipped when picking the next story
+	if next := p.NextIncomplete(); next == nil || next.ID != "US-002" {
+		t.Errorf("NextIncomplete = %v, want US-002", next)
+	}
+	if p.BlockedCount() != 1 {
+		t.Errorf("BlockedCount = %d, want 1", p.BlockedCount())
+	}
+}
+
+func TestRecordAttemptIgnoresPassingStory(t *testing.T) {
+	p := &PRD{UserStories: []UserStory{{ID: "US-001", Passes: true}}}
+
+	if blocked := p.RecordAttempt("US-001", "whatever", 1); blocked {
+		t.Fatal("passing story should never be blocked")
+	}
+	if p.UserStories[0].Attempts != 0 {
+		t.Errorf("attempts = %d, want 0", p.UserStories[0].Attempts)
+	}
+}
+
+func TestRecordResume(t *testing.T) {
+	p := &PRD{UserStories: []UserStory{{ID: "US-001"}}}
+
+	if n := p.RecordResume("US-001"); n != 1 {
+		t.Errorf("first resume = %d, want 1", n)
+	}
+	if n := p.RecordResume("US-001"); n != 2 {
+		t.Errorf("second resume = %d, want 2", n)
+	}
+	if n := p.RecordResume("US-404"); n != 0 {
+		t.Errorf("unknown story resume = %d, want 0", n)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	p := &PRD{UserStories: []UserStory{{ID: "US-001"}, {ID: "US-002", Passes: true}}}
+
+	if !p.Block("US-001", "needs an API key") {
+		t.Fatal("story not blocked")
+	}
+	if p.Block("US-001", "again") {
+		t.Error("blocked story blocked twice")
+	}
+	if p.Block("US-002", "whatever") {
+		t.Error("passing story should never be blocked")
+	}
+	if story := p.FindStory("US-001"); !story.Blocked || !strings.Contains(story.Notes, "needs an API key") {
+		t.Errorf("story = %+v, want blocked with the reason in its notes", story)
+	}
+}
+
+func TestNextIncompleteUnsetPriorityLast(t *testing.T) {
+	p := &PRD{UserStories: []UserStory{
+		{ID: "US-001"},
+		{ID: "US-002", Priority: 2},
+		{ID: "US-003"},
+		{ID: "US-004", Priority: 1},
+		{ID: "US-005", Priority: 1},
+	}}
+
+	var order []string
+	for next := p.NextIncomplete(); next != nil; next = p.NextIncomplete() {
+		order = append(order, next.ID)
+		next.Passes = true
+	}
+	want := "US-004 US-005 US-002 US-001 US-003"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,370 @@
+package progress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileName is the progress log kept in each project directory
+const FileName = "progress.txt"
+
+// BackupDir holds copies of progress.txt taken before compaction, relative
+// to the project directory. Archiving moves them into the archive.
+const BackupDir = "backups"
+
+// Section titles with special meaning in progress.txt
+const (
+	PatternsTitle = "Codebase Patterns"
+	IssuesTitle   = "Discovered Issues"
+	HistoryTitle  = "Compacted History"
+)
+
+// entrySeparator ends an iteration entry
+const entrySeparator = "---"
+
+// Issue is a bullet from the Discovered Issues section
+type Issue struct {
+	Text string
+	Done bool
+}
+
+// Entry is one iteration's report ("## [Date/Time] - [Story ID]" ... "---")
+type Entry struct {
+	Title   string
+	Date    string
+	StoryID string
+	Body    []string
+}
+
+// Log is a parsed progress.txt
+type Log struct {
+	Header   []string // Preamble before the first section ("# Progress Log", ...)
+	Patterns []string
+	Issues   []Issue
+	History  []string // One-line summaries of compacted entries
+	Entries  []Entry
+	Other    []string // Lines outside any known section or entry, kept as they are
+}
+
+// Path returns the progress.txt path in a project directory
+func Path(projectDir string) string {
+	return filepath.Join(projectDir, FileName)
+}
+
+// Load reads and parses progress.txt from a project directory
+func Load(projectDir string) (*Log, error) {
+	data, err := os.ReadFile(Path(projectDir))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data)), nil
+}
+
+// Save writes the log back to progress.txt in a project directory
+func (l *Log) Save(projectDir string) error {
+	return os.WriteFile(Path(projectDir), []byte(l.String()), 0644)
+}
+
+// Parse parses the contents of a progress.txt file. Anything that is not a
+// known section is treated as an iteration entry. Lines that fit neither
+// are kept in Other.
+func Parse(content string) *Log {
+	log := &Log{}
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	section := ""
+	seenSection := false
+	var entry *Entry
+	flushEntry := func() {
+		if entry != nil {
+			entry.Body = trimBlank(entry.Body)
+			log.Entries = append(log.Entries, *entry)
+			entry = nil
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "## ") {
+			flushEntry()
+			seenSection = true
+			title := strings.TrimSpace(strings.TrimPrefix(line, "## "))
+			switch title {
+			case PatternsTitle, IssuesTitle, HistoryTitle:
+				section = title
+			default:
+				section = ""
+				entry = newEntry(title)
+			}
+			continue
+		}
+
+		if entry != nil {
+			if trimmed == entrySeparator {
+				flushEntry()
+				continue
+			}
+			entry.Body = append(entry.Body, line)
+			continue
+		}
+
+		switch section {
+		case "":
+			// The preamble, or stray lines between entries
+			if !seenSection {
+				log.Header = append(log.Header, line)
+			} else if trimmed != "" {
+				log.Other = append(log.Other, line)
+			}
+		case PatternsTitle:
+			log.Patterns = appendBullet(log.Patterns, line)
+		case HistoryTitle:
+			log.History = appendBullet(log.History, line)
+		case IssuesTitle:
+			if strings.HasPrefix(trimmed, "- ") {
+				text := strings.TrimPrefix(trimmed, "- ")
+				issue := Issue{Text: text}
+				switch {
+				case strings.HasPrefix(text, "[ ] "):
+					issue.Text = strings.TrimPrefix(text, "[ ] ")
+				case strings.HasPrefix(strings.ToLower(text), "[x] "):
+					issue.Text = text[4:]
+					issue.Done = true
+				}
+				log.Issues = append(log.Issues, issue)
+			} else if trimmed != "" {
+				log.Other = append(log.Other, line)
+			}
+		}
+	}
+	flushEntry()
+	log.Header = trimBlank(log.Header)
+
+	return log
+}
+
+// newEntry parses an entry title of the form "<date> - <story id>"
+func newEntry(title string) *Entry {
+	e := &Entry{Title: title}
+	if i := strings.LastIndex(title, " - "); i >= 0 {
+		e.Date = strings.Trim(strings.TrimSpace(title[:i]), "[]")
+		e.StoryID = strings.Trim(strings.TrimSpace(title[i+3:]), "[]")
+	} else {
+		e.Date = strings.Trim(title, "[]")
+	}
+	return e
+}
+
+// appendBullet adds a "- item" line to a list, joining continuation lines
+// onto the previous item
+func appendBullet(items []string, line string) []string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return items
+	}
+	if strings.HasPrefix(trimmed, "- ") {
+		return append(items, strings.TrimPrefix(trimmed, "- "))
+	}
+	if len(items) > 0 {
+		items[len(items)-1] += " " + trimmed
+		return items
+	}
+	return append(items, trimmed)
+}
+
+// trimBlank removes leading and trailing blank lines
+func trimBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Learnings returns the bullets listed under "Learnings for future iterations"
+func (e Entry) Learnings() []string {
+	var learnings []string
+	inLearnings := false
+	for _, line := range e.Body {
+		trimmed := strings.TrimSpace(line)
+		if strings.Contains(strings.ToLower(trimmed), "learnings") {
+			inLearnings = true
+			continue
+		}
+		if !inLearnings {
+			continue
+		}
+		// Learnings are nested bullets; a top-level bullet ends the list
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			break
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			learnings = append(learnings, strings.TrimPrefix(trimmed, "- "))
+		}
+	}
+	return learnings
+}
+
+// Summary returns a one-line description of the entry
+func (e Entry) Summary() string {
+	what := ""
+	for _, line := range e.Body {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") && !strings.Contains(strings.ToLower(trimmed), "learnings") {
+			what = strings.TrimPrefix(trimmed, "- ")
+			break
+		}
+	}
+
+	parts := []string{}
+	if e.Date != "" {
+		parts = append(parts, e.Date)
+	}
+	if e.StoryID != "" {
+		parts = append(parts, e.StoryID)
+	}
+	summary := strings.Join(parts, " ")
+	if what != "" {
+		summary += ": " + what
+	}
+	return summary
+}
+
+// OpenIssues returns the number of unresolved discovered issues
+func (l *Log) OpenIssues() int {
+	count := 0
+	for _, issue := range l.Issues {
+		if !issue.Done {
+			count++
+		}
+	}
+	return count
+}
+
+// String renders the log in progress.txt format
+func (l *Log) String() string {
+	var b strings.Builder
+
+	if len(l.Header) > 0 {
+		b.WriteString(strings.Join(l.Header, "\n") + "\n\n")
+	}
+	// Stray lines go after the preamble, which keeps any text as it is
+	if len(l.Other) > 0 {
+		b.WriteString(strings.Join(l.Other, "\n") + "\n\n")
+	}
+
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "## %s\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+		b.WriteString("\n")
+	}
+
+	writeList(PatternsTitle, l.Patterns)
+
+	if len(l.Issues) > 0 {
+		fmt.Fprintf(&b, "## %s\n", IssuesTitle)
+		for _, issue := range l.Issues {
+			box := "[ ]"
+			if issue.Done {
+				box = "[x]"
+			}
+			fmt.Fprintf(&b, "- %s %s\n", box, issue.Text)
+		}
+		b.WriteString("\n")
+	}
+
+	writeList(HistoryTitle, l.History)
+
+	for _, e := range l.Entries {
+		fmt.Fprintf(&b, "## %s\n", e.Title)
+		for _, line := range e.Body {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString(entrySeparator + "\n\n")
+	}
+
+	return b.String()
+}
+
+// Compact folds old entries into the patterns and history sections. The
+// newest keep entries always stay; older ones are compacted, and then more
+// are compacted (down to one entry) until the log fits in maxBytes. As a last
+// resort the oldest history lines are dropped. Returns the number of entries
+// compacted.
+func (l *Log) Compact(maxBytes, keep int) int {
+	if keep < 1 {
+		keep = 1
+	}
+
+	compacted := 0
+	for len(l.Entries) > keep || (len(l.Entries) > 1 && maxBytes > 0 && len(l.String()) > maxBytes) {
+		l.compactOldest()
+		compacted++
+	}
+
+	for maxBytes > 0 && len(l.History) > 0 && len(l.String()) > maxBytes {
+		l.History = l.History[1:]
+	}
+
+	return compacted
+}
+
+// compactOldest moves the oldest entry's learnings into the patterns and
+// replaces the entry with a one-line history summary
+func (l *Log) compactOldest() {
+	oldest := l.Entries[0]
+	l.Entries = l.Entries[1:]
+
+	for _, learning := range oldest.Learnings() {
+		l.AddPattern(learning)
+	}
+	if summary := oldest.Summary(); summary != "" {
+		l.History = append(l.History, summary)
+	}
+}
+
+// AddPattern adds a codebase pattern unless an equivalent one already exists
+func (l *Log) AddPattern(pattern string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+	for _, existing := range l.Patterns {
+		if strings.EqualFold(strings.TrimSpace(existing), pattern) {
+			return false
+		}
+	}
+	l.Patterns = append(l.Patterns, pattern)
+	return true
+}
+
+// Backup copies the current progress.txt into the project's backup
+// directory and returns the backup path
+func Backup(projectDir string) (string, error) {
+	data, err := os.ReadFile(Path(projectDir))
+	if err != nil {
+		return "", err
+	}
+
+	backupDir := filepath.Join(projectDir, BackupDir)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(backupDir, fmt.Sprintf("progress-%s.txt", time.Now().Format("2006-01-02-15-04-05")))
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
@@ -0,0 +1,122 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `# Progress Log
+# Project: /code/app
+# Initialized: 2026-01-10 09:00:00
+
+## Codebase Patterns
+- Use sql<number> template for aggregations
+
+## 2026-01-10 10:00 - US-001
+Session: /code/app on branch ralph/tasks
+- Added status column
+- Files changed: db/schema.ts
+- **Learnings for future iterations:**
+  - Always use IF NOT EXISTS for migrations
+  - use sql<number> template for aggregations
+---
+
+## 2026-01-10 11:00 - US-002
+Session: /code/app on branch ralph/tasks
+- Added status badge
+- **Learnings for future iterations:**
+  - Badge colors live in theme.ts
+---
+
+## Discovered Issues
+- [ ] BUG: date picker ignores timezone - Found in picker.tsx - 2026-01-10
+- [x] BUG: stale cache - Found in api.ts - 2026-01-09
+`
+
+func TestParse(t *testing.T) {
+	log := Parse(sample)
+
+	if len(log.Header) != 3 {
+		t.Errorf("header lines = %d, want 3", len(log.Header))
+	}
+	if len(log.Patterns) != 1 {
+		t.Errorf("patterns = %v", log.Patterns)
+	}
+	if len(log.Entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(log.Entries))
+	}
+
+	first := log.Entries[0]
+	if first.Date != "2026-01-10 10:00" || first.StoryID != "US-001" {
+		t.Errorf("entry title parsed as %q / %q", first.Date, first.StoryID)
+	}
+	if learnings := first.Learnings(); len(learnings) != 2 {
+		t.Errorf("learnings = %v", learnings)
+	}
+	if log.OpenIssues() != 1 || len(log.Issues) != 2 {
+		t.Errorf("issues = %+v", log.Issues)
+	}
+
+	// Rendering and re-parsing keeps everything
+	again := Parse(log.String())
+	if len(again.Entries) != 2 || len(again.Patterns) != 1 || len(again.Issues) != 2 {
+		t.Errorf("round trip lost data:\n%s", log.String())
+	}
+}
+
+func TestCompactKeepsRecentEntries(t *testing.T) {
+	log := Parse(sample)
+
+	if n := log.Compact(0, 1); n != 1 {
+		t.Fatalf("compacted %d entries, want 1", n)
+	}
+	if len(log.Entries) != 1 || log.Entries[0].StoryID != "US-002" {
+		t.Errorf("remaining entries = %+v", log.Entries)
+	}
+
+	// The new learning is added, the duplicate pattern is not
+	if len(log.Patterns) != 2 || !strings.Contains(log.Patterns[1], "IF NOT EXISTS") {
+		t.Errorf("patterns = %v", log.Patterns)
+	}
+	if len(log.History) != 1 || !strings.HasPrefix(log.History[0], "2026-01-10 10:00 US-001: Added status column") {
+		t.Errorf("history = %v", log.History)
+	}
+}
+
+func TestCompactFitsBudget(t *testing.T) {
+	log := Parse(sample)
+	budget := len(log.String()) - 50
+
+	log.Compact(budget, 5)
+	if size := len(log.String()); size > budget {
+		t.Errorf("size %d exceeds budget %d", size, budget)
+	}
+	if len(log.Entries) == 0 {
+		t.Error("compaction removed every entry")
+	}
+}
+
+func TestParseKeepsUnknownLines(t *testing.T) {
+	content := sample + "\nNOTE: migrations run on deploy\n"
+	content = strings.Replace(content, "- [x] BUG: stale cache", "See also issues.md\n- [x] BUG: stale cache", 1)
+
+	log := Parse(content)
+	if len(log.Other) != 2 {
+		t.Fatalf("other = %q, want 2 lines", log.Other)
+	}
+	if len(log.Issues) != 2 {
+		t.Errorf("issues = %+v", log.Issues)
+	}
+
+	// Compacting and rewriting keeps them
+	log.Compact(0, 1)
+	out := log.String()
+	for _, line := range []string{"NOTE: migrations run on deploy", "See also issues.md"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("rewritten log lost %q:\n%s", line, out)
+		}
+	}
+	if again := Parse(out).String(); again != out {
+		t.Errorf("rewritten log changes on the next rewrite:\n%s\nvs\n%s", out, again)
+	}
+}
@@ -0,0 +1,103 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/kento/ralph/internal/prd"
+)
+
+// Story is the user story assigned to an iteration
+type Story struct {
+	ID                 string
+	Title              string
+	Description        string
+	AcceptanceCriteria []string
+	Priority           int
+	Notes              string
+	Attempt            int // 1 for the first attempt at this story
+}
+
+// Outcome describes how the previous iteration ended
+type Outcome struct {
+	Iteration int
+	StoryID   string
+	Success   bool
+	Reason    string // Why the story did not pass (empty on success)
+}
+
+// Data is everything available to prompt.md templates
+type Data struct {
+	ProjectDir    string
+	WorkingDir    string
+	Branch        string // Git branch checked out in WorkingDir
+	Iteration     int
+	MaxIterations int
+	PRD           *prd.PRD
+	Story         *Story   // nil when no story is left to work on
+	Previous      *Outcome // nil on the first iteration
+}
+
+// NewStory builds the template view of a PRD story
+func NewStory(s *prd.UserStory) *Story {
+	if s == nil {
+		return nil
+	}
+	return &Story{
+		ID:                 s.ID,
+		Title:              s.Title,
+		Description:        s.Description,
+		AcceptanceCriteria: s.AcceptanceCriteria,
+		Priority:           s.Priority,
+		Notes:              s.Notes,
+		Attempt:            s.Attempts + 1,
+	}
+}
+
+// Render executes a prompt template with the given data.
+// Unknown fields, functions and templates are reported as errors.
+func Render(name, text string, data Data) (string, error) {
+	tmpl, err := newTemplate(name, text, data)
+	if err != nil {
+		return "", err
+	}
+	return execute(tmpl, data)
+}
+
+// newTemplate parses the main prompt template
+func newTemplate(name, text string, data Data) (*template.Template, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(funcs(data)).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func execute(tmpl *template.Template, data Data) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return b.String(), nil
+}
+
+// funcs returns the helpers available to templates.
+// PROJECT_DIR and WORKING_DIR keep prompts written for the old
+// {{PROJECT_DIR}} placeholder syntax working.
+func funcs(data Data) template.FuncMap {
+	return template.FuncMap{
+		"PROJECT_DIR": func() string { return data.ProjectDir },
+		"WORKING_DIR": func() string { return data.WorkingDir },
+		"join":        strings.Join,
+	}
+}
+
+// EstimateTokens gives a rough token count for a prompt (about 4 bytes per
+// token for English text and code)
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,161 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kento/ralph/internal/prd"
+)
+
+func TestRenderStoryData(t *testing.T) {
+	story := &prd.UserStory{
+		ID:                 "US-002",
+		Title:              "Add status badge",
+		AcceptanceCriteria: []string{"Badge is green when done", "Typecheck passes"},
+		Notes:              "Attempt 1: typecheck failed",
+		Attempts:           1,
+	}
+
+	out, err := Render("prompt.md", "{{.Story.ID}} attempt {{.Story.Attempt}}: {{join .Story.AcceptanceCriteria \", \"}}", Data{
+		Story: NewStory(story),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "US-002 attempt 2: Badge is green when done, Typecheck passes"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestRenderLegacyPlaceholders(t *testing.T) {
+	out, err := Render("prompt.md", "{{PROJECT_DIR}}/prd.json in {{WORKING_DIR}}", Data{
+		ProjectDir: "/ralph/projects/app",
+		WorkingDir: "/code/app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "/ralph/projects/app/prd.json in /code/app" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestRenderUnknownVariable(t *testing.T) {
+	if _, err := Render("prompt.md", "{{.Stroy.ID}}", Data{}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+	if _, err := Render("prompt.md", "{{STORY_ID}}", Data{}); err == nil {
+		t.Error("expected error for unknown function")
+	}
+}
+
+// The shipped prompt.md must render both with and without a selected story
+func TestRenderShippedPrompt(t *testing.T) {
+	src, err := Resolve("../..", t.TempDir(), t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &prd.PRD{UserStories: []prd.UserStory{{ID: "US-001", Title: "First"}}}
+	for _, story := range []*Story{nil, NewStory(&p.UserStories[0])} {
+		out, err := src.Render(Data{ProjectDir: "/p", WorkingDir: "/w", PRD: p, Story: story})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out, "{{") {
+			t.Error("rendered prompt still contains template actions")
+		}
+		if strings.Contains(out, "## Browser Testing") {
+			t.Error("browser-testing partial included without browserTesting")
+		}
+	}
+
+	// Browser testing is opt-in per PRD
+	p.BrowserTesting = true
+	out, err := src.Render(Data{ProjectDir: "/p", WorkingDir: "/w", PRD: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "## Browser Testing") {
+		t.Error("browser-testing partial not included")
+	}
+	if !strings.Contains(out, "verification passes.\n\n## Stop Condition") {
+		t.Error("browser-testing partial not followed by a blank line")
+	}
+}
+
+func TestResolveLookupOrderAndPartials(t *testing.T) {
+	home, projectDir, workingDir := t.TempDir(), t.TempDir(), t.TempDir()
+	for _, dir := range []string{
+		filepath.Join(home, "partials"),
+		filepath.Join(workingDir, ".ralph", "partials"),
+		filepath.Join(projectDir, "prompts", "partials"),
+	} {
+		os.MkdirAll(dir, 0755)
+	}
+
+	os.WriteFile(filepath.Join(home, "prompt.md"), []byte("global"), 0644)
+	os.WriteFile(filepath.Join(home, "partials", "testing.md"), []byte("browser"), 0644)
+	os.WriteFile(filepath.Join(home, "partials", "rules.md"), []byte("global rules"), 0644)
+
+	src, err := Resolve(home, projectDir, workingDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Origin != "global" {
+		t.Errorf("origin = %s, want global", src.Origin)
+	}
+
+	// Repo prompt beats global, project prompt beats repo
+	os.WriteFile(filepath.Join(workingDir, ".ralph", "prompt.md"), []byte(`{{template "testing" .}}|{{template "rules" .}}`), 0644)
+	os.WriteFile(filepath.Join(workingDir, ".ralph", "partials", "testing.md"), []byte(""), 0644)
+	if src, _ = Resolve(home, projectDir, workingDir, nil); src.Origin != "repo" {
+		t.Errorf("origin = %s, want repo", src.Origin)
+	}
+
+	// An empty repo partial replaces the global one
+	out, err := src.Render(Data{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "|global rules" {
+		t.Errorf("got %q, want %q", out, "|global rules")
+	}
+
+	os.WriteFile(filepath.Join(projectDir, "prompt.md"), []byte("project"), 0644)
+	if src, _ = Resolve(home, projectDir, workingDir, nil); src.Origin != "project" {
+		t.Errorf("origin = %s, want project", src.Origin)
+	}
+
+	// The PRD prompt wins over everything
+	os.WriteFile(filepath.Join(projectDir, "backend.md"), []byte("backend"), 0644)
+	src, err = Resolve(home, projectDir, workingDir, &prd.PRD{Prompt: "backend.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Origin != "prd" {
+		t.Errorf("origin = %s, want prd", src.Origin)
+	}
+
+	// Partials next to the PRD prompt replace those of every level
+	os.WriteFile(filepath.Join(projectDir, "prompts", "api.md"), []byte(`{{template "api" .}}|{{template "rules" .}}`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "prompts", "partials", "api.md"), []byte("api"), 0644)
+	os.WriteFile(filepath.Join(projectDir, "prompts", "partials", "rules.md"), []byte("api rules"), 0644)
+	if src, err = Resolve(home, projectDir, workingDir, &prd.PRD{Prompt: "prompts/api.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = src.Render(Data{}); err != nil {
+		t.Fatal(err)
+	}
+	if out != "api|api rules" {
+		t.Errorf("got %q, want %q", out, "api|api rules")
+	}
+
+	if _, err := Resolve(home, projectDir, workingDir, &prd.PRD{Prompt: "missing.md"}); err == nil {
+		t.Error("expected error for missing PRD prompt")
+	}
+}
@@ -0,0 +1,126 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kento/ralph/internal/prd"
+)
+
+// PromptFile is the name of prompt templates at every level
+const PromptFile = "prompt.md"
+
+// partialsDir is the directory holding named partials next to a prompt.md
+const partialsDir = "partials"
+
+// Source is a resolved prompt template and the partials it can include
+type Source struct {
+	Path     string            // Main template file
+	Origin   string            // Which level the main template came from
+	Partials map[string]string // Partial name -> file, most specific level wins
+}
+
+// level is one place prompts and partials can be defined
+type level struct {
+	origin string
+	dir    string // Directory containing prompt.md and partials/
+}
+
+// Resolve finds the prompt template for a run. Lookup order is:
+//  1. the prompt set in the PRD's "prompt" field
+//  2. <project dir>/prompt.md
+//  3. <repo>/.ralph/prompt.md
+//  4. <RALPH_HOME>/prompt.md
+//
+// Partials are *.md files in a partials/ directory at the project, repo and
+// global levels, and next to the PRD's prompt. A partial at a more specific
+// level replaces one with the same name at a less specific level.
+func Resolve(ralphHome, projectDir, workingDir string, p *prd.PRD) (*Source, error) {
+	levels := []level{
+		{origin: "project", dir: projectDir},
+		{origin: "repo", dir: filepath.Join(workingDir, ".ralph")},
+		{origin: "global", dir: ralphHome},
+	}
+
+	src := &Source{Partials: map[string]string{}}
+
+	if p != nil && p.Prompt != "" {
+		path := p.Prompt
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("prompt %q set in prd.json not found: %w", p.Prompt, err)
+		}
+		src.Path = path
+		src.Origin = "prd"
+		// Partials next to the PRD's prompt are the most specific
+		if dir := filepath.Dir(path); dir != filepath.Clean(projectDir) {
+			levels = append([]level{{origin: "prd", dir: dir}}, levels...)
+		}
+	} else {
+		for _, l := range levels {
+			path := filepath.Join(l.dir, PromptFile)
+			if _, err := os.Stat(path); err == nil {
+				src.Path = path
+				src.Origin = l.origin
+				break
+			}
+		}
+	}
+
+	if src.Path == "" {
+		return nil, fmt.Errorf("prompt.md not found at %s", filepath.Join(ralphHome, PromptFile))
+	}
+
+	// Collect partials from the least to the most specific level so that
+	// later levels override earlier ones
+	for i := len(levels) - 1; i >= 0; i-- {
+		files, _ := filepath.Glob(filepath.Join(levels[i].dir, partialsDir, "*.md"))
+		for _, file := range files {
+			name := strings.TrimSuffix(filepath.Base(file), ".md")
+			src.Partials[name] = file
+		}
+	}
+
+	return src, nil
+}
+
+// PartialNames returns the available partial names in sorted order
+func (s *Source) PartialNames() []string {
+	names := make([]string, 0, len(s.Partials))
+	for name := range s.Partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render reads the template and its partials and executes it with data.
+// Partials are included with {{template "name" .}}.
+func (s *Source) Render(data Data) (string, error) {
+	text, err := os.ReadFile(s.Path)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := newTemplate(filepath.Base(s.Path), string(text), data)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range s.PartialNames() {
+		content, err := os.ReadFile(s.Partials[name])
+		if err != nil {
+			return "", err
+		}
+		if _, err := tmpl.New(name).Parse(string(content)); err != nil {
+			return "", fmt.Errorf("invalid partial %s: %w", s.Partials[name], err)
+		}
+	}
+
+	return execute(tmpl, data)
+}
@@ -0,0 +1,242 @@
+package runlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DateFormat is the timestamp in run log file names
+const DateFormat = "2006-01-02-15-04-05"
+
+// MarkerFile marks a run that is writing logs. It is removed when the run
+// shuts down cleanly, so a marker left behind by a dead process means the
+// run crashed.
+const MarkerFile = ".active"
+
+// syncInterval bounds how much output a machine crash can lose. Every
+// write reaches the OS right away, so a killed process loses nothing; only
+// a reboot or power loss can.
+const syncInterval = time.Second
+
+// syncEvents are flushed to disk right away: the events that tell how an
+// iteration or the run ended are the ones that explain a crash
+var syncEvents = map[string]bool{
+	"result":    true,
+	"error":     true,
+	"iteration": true,
+	"signal":    true,
+	"done":      true,
+}
+
+// Event is a line of the structured (.jsonl) log
+type Event struct {
+	Time      time.Time `json:"time"`
+	Iteration int       `json:"iteration,omitempty"`
+	Story     string    `json:"story,omitempty"`
+	Type      string    `json:"type"`
+	Tool      string    `json:"tool,omitempty"`
+	Context   string    `json:"context,omitempty"`
+	Text      string    `json:"text,omitempty"`
+	Success   bool      `json:"success,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Data      any       `json:"data,omitempty"` // Details of the event, e.g. the session of init
+}
+
+// Marker is the content of the .active marker
+type Marker struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Log     string    `json:"log"`
+	Events  string    `json:"events"`
+}
+
+// Logger writes a run's logs while it happens: the rendered output as
+// shown in the TUI (.log) and one JSON event per line (.jsonl)
+type Logger struct {
+	LogPath    string
+	EventsPath string
+
+	mu       sync.Mutex
+	text     *os.File
+	events   *os.File
+	marker   string
+	lastSync time.Time
+}
+
+// Open creates the log files for a run on branch in logsDir and marks the
+// run as active
+func Open(logsDir, branch string, now time.Time) (*Logger, error) {
+	base := filepath.Join(logsDir, fmt.Sprintf("%s_%s", branch, now.Format(DateFormat)))
+
+	// Create all parent directories (handles branch names with slashes like "ralph/feature")
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return nil, err
+	}
+
+	l := &Logger{
+		LogPath:    base + ".log",
+		EventsPath: base + ".jsonl",
+		marker:     filepath.Join(logsDir, MarkerFile),
+		lastSync:   now,
+	}
+
+	var err error
+	if l.text, err = openAppend(l.LogPath); err != nil {
+		return nil, err
+	}
+	if l.events, err = openAppend(l.EventsPath); err != nil {
+		l.text.Close()
+		return nil, err
+	}
+
+	marker := Marker{PID: os.Getpid(), Started: now, Log: l.LogPath, Events: l.EventsPath}
+	if err := writeMarker(l.marker, marker); err != nil {
+		l.text.Close()
+		l.events.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// openExisting opens a file for appending without creating it
+func openExisting(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+}
+
+func writeMarker(path string, marker Marker) error {
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Write appends rendered output to the .log file
+func (l *Logger) Write(text string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.text == nil {
+		return os.ErrClosed
+	}
+	if _, err := l.text.WriteString(text); err != nil {
+		return err
+	}
+	l.maybeSync()
+	return nil
+}
+
+// Event appends an event to the .jsonl file
+func (l *Logger) Event(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.events == nil {
+		return os.ErrClosed
+	}
+	if _, err := l.events.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if syncEvents[e.Type] {
+		l.sync()
+	} else {
+		l.maybeSync()
+	}
+	return nil
+}
+
+// maybeSync flushes both files to disk at most once per syncInterval
+func (l *Logger) maybeSync() {
+	if time.Since(l.lastSync) >= syncInterval {
+		l.sync()
+	}
+}
+
+// sync flushes both files to disk
+func (l *Logger) sync() {
+	l.text.Sync()
+	l.events.Sync()
+	l.lastSync = time.Now()
+}
+
+// Close flushes and closes the logs and removes the active marker
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.text == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, f := range []*os.File{l.text, l.events} {
+		errs = append(errs, f.Sync(), f.Close())
+	}
+	l.text, l.events = nil, nil
+
+	if err := os.Remove(l.marker); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Active returns the marker of the run writing logs in logsDir, if any
+func Active(logsDir string) (*Marker, error) {
+	data, err := os.ReadFile(filepath.Join(logsDir, MarkerFile))
+	if err != nil {
+		return nil, err
+	}
+	var marker Marker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+// RecoverCrash looks for a marker left by a run whose process is gone. Its
+// logs get notice and a "crash" event appended, unless they were moved away
+// (archived) since, and the marker is removed. Returns the crashed run's
+// marker, or nil if there was none.
+func RecoverCrash(logsDir, notice string) (*Marker, error) {
+	marker, err := Active(logsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		// Unreadable marker: nothing to recover, don't let it stick around
+		os.Remove(filepath.Join(logsDir, MarkerFile))
+		return nil, nil
+	}
+	if marker.PID == os.Getpid() || processAlive(marker.PID) {
+		return nil, nil
+	}
+
+	if f, err := openExisting(marker.Log); err == nil {
+		f.WriteString("\n" + notice + "\n")
+		f.Close()
+	}
+	if f, err := openExisting(marker.Events); err == nil {
+		data, _ := json.Marshal(Event{Time: time.Now(), Type: "crash", Error: notice})
+		f.Write(append(data, '\n'))
+		f.Close()
+	}
+
+	if err := os.Remove(filepath.Join(logsDir, MarkerFile)); err != nil {
+		return marker, err
+	}
+	return marker, nil
+}
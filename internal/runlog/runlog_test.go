@@ -0,0 +1,133 @@
+package runlog
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerWritesIncrementally(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir, "ralph/feature", time.Date(2026, 1, 11, 15, 4, 5, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := filepath.Join(dir, "ralph", "feature_2026-01-11-15-04-05.log"); l.LogPath != want {
+		t.Errorf("LogPath = %s, want %s", l.LogPath, want)
+	}
+	if marker, err := Active(dir); err != nil || marker.Log != l.LogPath {
+		t.Fatalf("Active() = %v, %v", marker, err)
+	}
+
+	l.Write("first line\n")
+	l.Event(Event{Type: "tool_call", Tool: "Read"})
+
+	// Both files are readable before Close
+	if data, _ := os.ReadFile(l.LogPath); string(data) != "first line\n" {
+		t.Errorf("log = %q", data)
+	}
+	data, _ := os.ReadFile(l.EventsPath)
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil || event.Tool != "Read" || event.Time.IsZero() {
+		t.Errorf("event = %+v, %v", event, err)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Active(dir); !os.IsNotExist(err) {
+		t.Errorf("marker left after Close: %v", err)
+	}
+}
+
+func TestLoggerSyncsEndOfIteration(t *testing.T) {
+	now := time.Now()
+	l, err := Open(t.TempDir(), "main", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Within syncInterval only the events that end an iteration are synced
+	l.Event(Event{Type: "tool_call", Tool: "Read"})
+	if !l.lastSync.Equal(now) {
+		t.Errorf("tool_call synced the logs")
+	}
+	for _, typ := range []string{"result", "iteration"} {
+		before := l.lastSync
+		l.Event(Event{Type: typ})
+		if !l.lastSync.After(before) {
+			t.Errorf("%s didn't sync the logs", typ)
+		}
+	}
+}
+
+func TestRecoverCrash(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir, "feature", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Write("working...\n")
+
+	// Pretend the run belonged to a process that is gone
+	cmd := exec.Command("go", "version")
+	if err := cmd.Run(); err != nil {
+		t.Skip("cannot start a process:", err)
+	}
+	marker, _ := Active(dir)
+	marker.PID = cmd.Process.Pid
+	writeMarker(filepath.Join(dir, MarkerFile), *marker)
+
+	crashed, err := RecoverCrash(dir, "CRASHED")
+	if err != nil || crashed == nil {
+		t.Fatalf("RecoverCrash() = %v, %v", crashed, err)
+	}
+	if data, _ := os.ReadFile(l.LogPath); !strings.HasSuffix(string(data), "\nCRASHED\n") {
+		t.Errorf("log = %q, want the crash notice at the end", data)
+	}
+	if data, _ := os.ReadFile(l.EventsPath); !strings.Contains(string(data), `"type":"crash"`) {
+		t.Errorf("events = %q, want a crash event", data)
+	}
+	if _, err := Active(dir); !os.IsNotExist(err) {
+		t.Errorf("marker not removed: %v", err)
+	}
+
+	// A live run is left alone
+	writeMarker(filepath.Join(dir, MarkerFile), Marker{PID: os.Getppid(), Log: l.LogPath})
+	if crashed, _ := RecoverCrash(dir, "CRASHED"); crashed != nil {
+		t.Errorf("RecoverCrash() recovered a live run")
+	}
+	l.Close()
+}
+
+func TestRecoverCrashWithMovedLogs(t *testing.T) {
+	dir := t.TempDir()
+	cmd := exec.Command("go", "version")
+	if err := cmd.Run(); err != nil {
+		t.Skip("cannot start a process:", err)
+	}
+
+	// The crashed run's logs were archived before the next run
+	logPath := filepath.Join(dir, "feature_2026-01-11-10-00-00.log")
+	eventsPath := filepath.Join(dir, "feature_2026-01-11-10-00-00.jsonl")
+	writeMarker(filepath.Join(dir, MarkerFile), Marker{PID: cmd.Process.Pid, Log: logPath, Events: eventsPath})
+
+	crashed, err := RecoverCrash(dir, "CRASHED")
+	if err != nil || crashed == nil {
+		t.Fatalf("RecoverCrash() = %v, %v", crashed, err)
+	}
+	for _, path := range []string{logPath, eventsPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s recreated for the crash notice: %v", filepath.Base(path), err)
+		}
+	}
+	if _, err := Active(dir); !os.IsNotExist(err) {
+		t.Errorf("marker not removed: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package runstate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileName is the run state file kept in each project directory
+const FileName = "run-state.json"
+
+// State accumulates information about the runs of the active PRD so it can
+// be recorded when the run is archived
+type State struct {
+	Branch      string    `json:"branch"`
+	StartedAt   time.Time `json:"startedAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	Iterations  int       `json:"iterations"`
+	CostUSD     float64   `json:"costUsd"`
+	StartCommit string    `json:"startCommit,omitempty"`
+	Logs        []string  `json:"logs,omitempty"` // Run logs, relative to the project dir
+	Sessions    []Session `json:"sessions,omitempty"`
+}
+
+// Session records which agent session and model worked on a story
+type Session struct {
+	Iteration int       `json:"iteration"`
+	Story     string    `json:"story,omitempty"`
+	SessionID string    `json:"sessionId"`
+	Model     string    `json:"model,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// Path returns the run state path in a project directory
+func Path(projectDir string) string {
+	return filepath.Join(projectDir, FileName)
+}
+
+// Load reads the run state of a project. A missing file yields an empty state.
+func Load(projectDir string) (*State, error) {
+	data, err := os.ReadFile(Path(projectDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{}, nil
+		}
+		return nil, err
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save writes the run state to the project directory
+func (s *State) Save(projectDir string) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(Path(projectDir), append(data, '\n'), 0644)
+}
+
+// Remove deletes the run state of a project
+func Remove(projectDir string) error {
+	err := os.Remove(Path(projectDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Begin prepares the state for a run on branch. State left over from a
+// different branch is discarded.
+func (s *State) Begin(branch, headCommit string) {
+	if s.Branch != branch {
+		*s = State{Branch: branch}
+	}
+	if s.StartedAt.IsZero() {
+		s.StartedAt = time.Now()
+	}
+	if s.StartCommit == "" {
+		s.StartCommit = headCommit
+	}
+}
+
+// AddIteration records a finished iteration and its cost
+func (s *State) AddIteration(costUSD float64) {
+	s.Iterations++
+	s.CostUSD += costUSD
+}
+
+// AddSession records the agent session of an iteration
+func (s *State) AddSession(session Session) {
+	s.Sessions = append(s.Sessions, session)
+}
+
+// AddLog records a run log path (relative to the project dir)
+func (s *State) AddLog(path string) {
+	for _, existing := range s.Logs {
+		if existing == path {
+			return
+		}
+	}
+	s.Logs = append(s.Logs, path)
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Failure is a known reason for the agent to fail, recognized in its stderr
+// or in the text of an error result
+type Failure int
+
+const (
+	FailureNone Failure = iota
+	FailureAuth
+	FailureRateLimit
+	FailureOverloaded
+	FailureUsageLimit
+	FailureCommandNotFound
+)
+
+// String describes the failure for iteration outcomes and logs
+func (f Failure) String() string {
+	switch f {
+	case FailureAuth:
+		return "authentication failed"
+	case FailureRateLimit:
+		return "rate limited"
+	case FailureOverloaded:
+		return "API overloaded"
+	case FailureUsageLimit:
+		return "usage limit reached"
+	case FailureCommandNotFound:
+		return "command not found"
+	default:
+		return ""
+	}
+}
+
+// Throttled reports whether the failure is the API turning requests away
+// for a while (rate limit, overload, usage limit) rather than a problem
+// with the iteration itself
+func (f Failure) Throttled() bool {
+	return f == FailureRateLimit || f == FailureOverloaded || f == FailureUsageLimit
+}
+
+// failurePatterns match the error messages of the Claude CLI and API, not
+// just any mention of a limit, since agent stderr also carries the output of
+// the tools it runs. The first match wins.
+var failurePatterns = []struct {
+	pattern *regexp.Regexp
+	failure Failure
+}{
+	// "Invalid API key · Please run /login", "API Error: 401 {...}"
+	{regexp.MustCompile(`(?im)^(?:error: )?invalid api key\b|please run /login|oauth token has expired|"type":"authentication_error"|^API Error: 401\b`), FailureAuth},
+	// "Claude AI usage limit reached|1760000000", "5-hour limit reached ∙ resets 3pm"
+	{regexp.MustCompile(`(?im)^claude ai usage limit reached|^(?:5-hour|weekly|opus weekly) limit reached\b|^you've hit your (?:usage )?limit\b`), FailureUsageLimit},
+	// "API Error: 429 {"type":"error","error":{"type":"rate_limit_error",...}}"
+	{regexp.MustCompile(`(?im)^API Error: 429\b|"type":"rate_limit_error"`), FailureRateLimit},
+	// "API Error: 529 {"type":"error","error":{"type":"overloaded_error",...}}"
+	{regexp.MustCompile(`(?im)^API Error: 529\b|"type":"overloaded_error"`), FailureOverloaded},
+	// "/bin/sh: claude: command not found", "exec: "claude": executable file not found in $PATH"
+	{regexp.MustCompile(`(?i)\bclaude"?: (?:command )?not found|"claude": executable file not found`), FailureCommandNotFound},
+}
+
+// Classify returns the known failure a line of agent stderr or an error
+// result points at, or FailureNone
+func Classify(text string) Failure {
+	for _, p := range failurePatterns {
+		if p.pattern.MatchString(text) {
+			return p.failure
+		}
+	}
+	return FailureNone
+}
+
+var (
+	// "Claude AI usage limit reached|1760000000"
+	resetEpochPattern = regexp.MustCompile(`\|(\d{10})\b`)
+	// "5-hour limit reached ∙ resets 3pm (Europe/Paris)", "resets at 15:30 UTC"
+	resetClockPattern = regexp.MustCompile(`(?i)resets?(?: at)? (\d{1,2})(?::(\d{2}))? ?(am|pm)?\b(?: \(([\w/+-]+)\)| (UTC|GMT)\b)?`)
+	// "retry after 30 seconds", "try again in 5 minutes"
+	resetAfterPattern = regexp.MustCompile(`(?i)(?:retry|try again) (?:after|in) (\d+) ?(s|sec|secs|seconds?|m|min|mins|minutes?|h|hours?)\b`)
+)
+
+// ResetTime returns when a rate or usage limit announced in text resets,
+// or the zero time when text announces none. Clock times without a date
+// are the next occurrence in the time zone that follows them, or else in
+// now's location.
+func ResetTime(text string, now time.Time) time.Time {
+	if m := resetEpochPattern.FindStringSubmatch(text); m != nil {
+		if sec, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+
+	if m := resetAfterPattern.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Second
+		switch strings.ToLower(m[2])[0] {
+		case 'm':
+			unit = time.Minute
+		case 'h':
+			unit = time.Hour
+		}
+		return now.Add(time.Duration(n) * unit)
+	}
+
+	if m := resetClockPattern.FindStringSubmatch(text); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		switch strings.ToLower(m[3]) {
+		case "am":
+			hour %= 12
+		case "pm":
+			hour = hour%12 + 12
+		}
+		if hour > 23 || minute > 59 {
+			return time.Time{}
+		}
+		loc := now.Location()
+		if m[5] != "" {
+			loc = time.UTC
+		} else if m[4] != "" {
+			if l, err := time.LoadLocation(m[4]); err == nil {
+				loc = l
+			}
+		}
+		now := now.In(loc)
+		reset := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+		if !reset.After(now) {
+			reset = reset.AddDate(0, 0, 1)
+		}
+		return reset
+	}
+
+	return time.Time{}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	streamingjson "github.com/karminski/streaming-json-go"
 )
@@ -17,30 +18,87 @@ const (
 	OutputToolCall
 	OutputResult
 	OutputError
+	OutputWarning
+	OutputToolResult
+	OutputInit
 )
 
-// Parser handles parsing of stream-json output from Claude
-type Parser struct{}
+// String returns the output type's name, as used in structured logs
+func (t OutputType) String() string {
+	switch t {
+	case OutputToolCall:
+		return "tool_call"
+	case OutputResult:
+		return "result"
+	case OutputError:
+		return "error"
+	case OutputWarning:
+		return "warning"
+	case OutputToolResult:
+		return "tool_result"
+	case OutputInit:
+		return "init"
+	default:
+		return "text"
+	}
+}
+
+// Parser handles parsing of stream-json output from Claude. It keeps the
+// content blocks of the message being streamed, so partial output and the
+// complete message that follows it share a BlockID.
+type Parser struct {
+	messageID string
+	messages  int                   // Messages started, numbers messages without an ID
+	blocks    map[int]*partialBlock // Blocks of the streamed message by index
+	order     []*partialBlock
+	rules     []ToolRule // Display rules tried before DefaultToolRules
+}
+
+// partialBlock is a content block received through delta events
+type partialBlock struct {
+	id     string // BlockID
+	kind   string // "text" or "tool_use"
+	toolID string
+	name   string
+	text   strings.Builder
+	input  strings.Builder // Partial JSON of tool input
+	done   bool            // Matched to the complete message
+}
 
-// NewParser creates a new stream parser
-func NewParser() *Parser {
-	return &Parser{}
+// NewParser creates a new stream parser. Tool calls are shown by the given
+// display rules, then by DefaultToolRules.
+func NewParser(rules ...ToolRule) *Parser {
+	return &Parser{blocks: make(map[int]*partialBlock), rules: rules}
 }
 
 // ParseResult holds the formatted output from parsing
 type ParseResult struct {
-	Display  string     // Formatted string for display
-	Type     OutputType // Type of output for styling
-	ToolName string     // Tool name for tool calls
-	Context  string     // Context info for tool calls
-	IsEmpty  bool       // True if nothing to display
+	Display   string     // Formatted string for display
+	Type      OutputType // Type of output for styling
+	ToolName  string     // Tool name for tool calls
+	ToolStyle ToolStyle  // Label, color and icon of the tool from its display rule
+	ToolID    string     // tool_use ID, linking a tool result to its call
+	BlockID   string     // Content block the output belongs to, when streamed
+	Partial   bool       // Incomplete output of a block still being streamed
+	Context   string     // Context info for tool calls
+	IsError   bool       // Tool result reported an error
+	CostUSD   float64    // Total cost reported by result events
+	Session   *Session   // Session metadata of init events
+	SessionID string     // Session ID reported by result events
+	Failure   Failure    // Known failure reported by an error result
+	ResetAt   time.Time  // When the limit behind a throttled failure resets, if announced
+	Promise   Promise    // Stop signal in a <promise> tag of complete text or a result
+	Reason    string     // Reason given with the promise
+	ParentID  string     // tool_use ID of the Task call, for output of a sub-agent
+	Todos     []Todo     // The main agent's todo list, from a complete TodoWrite call
 }
 
-// ParseLine parses a JSON line and returns formatted output
-func (p *Parser) ParseLine(line string) ParseResult {
+// ParseLine parses a JSON line and returns its formatted output, one result
+// per content block in order. Lines with nothing to display return none.
+func (p *Parser) ParseLine(line string) []ParseResult {
 	line = strings.TrimSpace(line)
 	if line == "" {
-		return ParseResult{IsEmpty: true}
+		return nil
 	}
 
 	// Use streaming-json-go to complete potentially incomplete JSON
@@ -51,62 +109,238 @@ func (p *Parser) ParseLine(line string) ParseResult {
 	// First, determine the event type
 	var event StreamEvent
 	if err := json.Unmarshal([]byte(completedJSON), &event); err != nil {
-		// If we can't parse at all, return raw line
-		return ParseResult{Display: line}
+		// An event we can't decode is reported instead of dumped raw
+		if strings.HasPrefix(line, "{") {
+			return []ParseResult{{
+				Display: fmt.Sprintf("Skipped undecodable stream event (%d bytes): %v", len(line), err),
+				Type:    OutputWarning,
+			}}
+		}
+		// Plain text, return raw line
+		return []ParseResult{{Display: line}}
 	}
 
 	switch event.Type {
+	case "stream_event":
+		return p.parsePartial(completedJSON)
 	case "assistant":
 		return p.parseAssistant(completedJSON)
 	case "user":
-		// User messages are typically tool results - skip verbose output
-		return ParseResult{IsEmpty: true}
+		return p.parseUser(completedJSON)
 	case "system":
-		// Skip system messages (init, hooks, etc.)
-		return ParseResult{IsEmpty: true}
+		// Only init matters; skip hooks and the like
+		if event.Subtype == "init" {
+			return p.parseInit(completedJSON)
+		}
+		return nil
 	case "result":
 		return p.parseResult(completedJSON)
 	default:
-		return ParseResult{IsEmpty: true}
+		return nil
 	}
 }
 
-func (p *Parser) parseAssistant(jsonStr string) ParseResult {
+func (p *Parser) parseAssistant(jsonStr string) []ParseResult {
 	var event AssistantEvent
 	if err := json.Unmarshal([]byte(jsonStr), &event); err != nil {
-		return ParseResult{IsEmpty: true}
+		return nil
 	}
 
-	// Process content blocks - prioritize tool calls
+	// One result per content block: text followed by parallel tool calls
+	// all show up, in the order Claude sent them
+	var results []ParseResult
 	for _, block := range event.Message.Content {
 		switch block.Type {
 		case "tool_use":
-			context := extractToolContext(block.Name, block.Input)
-			return ParseResult{
-				Display:  context,
-				Type:     OutputToolCall,
-				ToolName: block.Name,
-				Context:  context,
-				IsEmpty:  false,
-			}
+			context := p.toolContext(block.Name, block.Input)
+			results = append(results, ParseResult{
+				Display:   context,
+				Type:      OutputToolCall,
+				ToolName:  block.Name,
+				ToolStyle: p.toolStyle(block.Name),
+				ToolID:    block.ID,
+				BlockID:   p.streamedBlock(event.Message.ID, block),
+				Context:   context,
+				ParentID:  event.ParentToolUseID,
+				Todos:     todoList(block, event.ParentToolUseID),
+			})
 		case "text":
 			if block.Text != "" {
-				return ParseResult{
-					Display: block.Text,
-					Type:    OutputText,
-					IsEmpty: false,
+				result := ParseResult{
+					Display:  block.Text,
+					Type:     OutputText,
+					BlockID:  p.streamedBlock(event.Message.ID, block),
+					ParentID: event.ParentToolUseID,
+				}
+				// Only the main agent can end the iteration
+				if event.ParentToolUseID == "" {
+					result.Promise, result.Reason = FindPromise(block.Text)
 				}
+				results = append(results, result)
 			}
 		}
 	}
 
-	return ParseResult{IsEmpty: true}
+	return results
+}
+
+// streamedBlock returns the BlockID under which a block of a complete
+// message was streamed, or "" if it wasn't. Tool calls match by ID, text
+// blocks in order.
+func (p *Parser) streamedBlock(messageID string, block ContentBlock) string {
+	if messageID == "" || messageID != p.messageID {
+		return ""
+	}
+	for _, b := range p.order {
+		if b.done || b.kind != block.Type || (b.kind == "tool_use" && b.toolID != block.ID) {
+			continue
+		}
+		b.done = true
+		return b.id
+	}
+	return ""
+}
+
+// parsePartial turns streaming events into partial results carrying all
+// of a block received so far. Tool inputs are completed with the
+// streaming JSON lexer to show their context while still incomplete.
+func (p *Parser) parsePartial(jsonStr string) []ParseResult {
+	var partial PartialEvent
+	if err := json.Unmarshal([]byte(jsonStr), &partial); err != nil {
+		return nil
+	}
+	// Sub-agents show up with their complete messages; streaming them would
+	// mix their blocks into those of the main agent
+	if partial.ParentToolUseID != "" {
+		return nil
+	}
+	event := partial.Event
+
+	switch event.Type {
+	case "message_start":
+		p.messages++
+		p.messageID = event.Message.ID
+		p.blocks = make(map[int]*partialBlock)
+		p.order = nil
+		return nil
+
+	case "content_block_start":
+		kind := event.ContentBlock.Type
+		if kind != "text" && kind != "tool_use" {
+			return nil
+		}
+		id := p.messageID
+		if id == "" {
+			id = fmt.Sprintf("message-%d", p.messages)
+		}
+		b := &partialBlock{
+			id:     fmt.Sprintf("%s/%d", id, event.Index),
+			kind:   kind,
+			toolID: event.ContentBlock.ID,
+			name:   event.ContentBlock.Name,
+		}
+		p.blocks[event.Index] = b
+		p.order = append(p.order, b)
+		if kind == "tool_use" {
+			return []ParseResult{p.partialResult(b)}
+		}
+		return nil
+
+	case "content_block_delta":
+		b, ok := p.blocks[event.Index]
+		if !ok {
+			return nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			b.text.WriteString(event.Delta.Text)
+		case "input_json_delta":
+			b.input.WriteString(event.Delta.PartialJSON)
+		default:
+			return nil
+		}
+		if b.kind == "text" && b.text.Len() == 0 {
+			return nil
+		}
+		return []ParseResult{p.partialResult(b)}
+	}
+
+	return nil
 }
 
-func (p *Parser) parseResult(jsonStr string) ParseResult {
+// partialResult renders a block as received so far
+func (p *Parser) partialResult(b *partialBlock) ParseResult {
+	if b.kind == "text" {
+		return ParseResult{Display: b.text.String(), Type: OutputText, BlockID: b.id, Partial: true}
+	}
+
+	var input map[string]any
+	if b.input.Len() > 0 {
+		lexer := streamingjson.NewLexer()
+		lexer.AppendString(b.input.String())
+		json.Unmarshal([]byte(lexer.CompleteJSON()), &input)
+	}
+	context := p.toolContext(b.name, input)
+	return ParseResult{
+		Display:   context,
+		Type:      OutputToolCall,
+		ToolName:  b.name,
+		ToolStyle: p.toolStyle(b.name),
+		ToolID:    b.toolID,
+		BlockID:   b.id,
+		Context:   context,
+		Partial:   true,
+	}
+}
+
+// parseUser returns the tool results of a user message. Only errors carry
+// their output, shortened to a snippet; successful output stays hidden.
+func (p *Parser) parseUser(jsonStr string) []ParseResult {
+	var event UserEvent
+	if err := json.Unmarshal([]byte(jsonStr), &event); err != nil {
+		return nil
+	}
+
+	var results []ParseResult
+	for _, block := range event.Message.Content {
+		if block.Type != "tool_result" || block.ToolUseID == "" {
+			continue
+		}
+		result := ParseResult{
+			Type:     OutputToolResult,
+			ToolID:   block.ToolUseID,
+			IsError:  block.IsError,
+			ParentID: event.ParentToolUseID,
+		}
+		if block.IsError {
+			result.Display = snippet(string(block.Content), 3, 120)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// parseInit captures the session metadata of the init event
+func (p *Parser) parseInit(jsonStr string) []ParseResult {
+	var event SystemEvent
+	if err := json.Unmarshal([]byte(jsonStr), &event); err != nil {
+		return nil
+	}
+
+	session := &Session{
+		ID:         event.SessionID,
+		Model:      event.Model,
+		CWD:        event.CWD,
+		Tools:      event.Tools,
+		MCPServers: event.MCPServers,
+	}
+	return []ParseResult{{Display: session.Summary(), Type: OutputInit, Session: session}}
+}
+
+func (p *Parser) parseResult(jsonStr string) []ParseResult {
 	var result ResultEvent
 	if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-		return ParseResult{IsEmpty: true}
+		return nil
 	}
 
 	status := result.Subtype
@@ -126,80 +360,26 @@ func (p *Parser) parseResult(jsonStr string) ParseResult {
 		parts = append(parts, fmt.Sprintf("(%d turns)", result.NumTurns))
 	}
 
-	return ParseResult{
-		Display: strings.Join(parts, " "),
-		Type:    OutputResult,
-		IsEmpty: false,
-	}
-}
-
-// extractToolContext extracts a brief context string from tool input
-func extractToolContext(toolName string, input map[string]any) string {
-	if input == nil {
-		return ""
+	parsed := ParseResult{
+		Type:      OutputResult,
+		CostUSD:   result.CostUSD,
+		SessionID: result.SessionID,
 	}
-
-	switch toolName {
-	case "Read", "Write", "Edit":
-		if path, ok := input["file_path"].(string); ok {
-			return shortenPath(path)
-		}
-	case "Bash":
-		if cmd, ok := input["command"].(string); ok {
-			return truncate(cmd, 50)
-		}
-	case "Glob", "Grep":
-		if pattern, ok := input["pattern"].(string); ok {
-			return truncate(pattern, 40)
-		}
-	case "Task":
-		if desc, ok := input["description"].(string); ok {
-			return truncate(desc, 40)
-		}
-	case "TodoWrite":
-		return "updating tasks"
-	case "Skill":
-		if skill, ok := input["skill"].(string); ok {
-			return skill
-		}
-	case "WebFetch":
-		if url, ok := input["url"].(string); ok {
-			return truncate(url, 50)
+	parsed.Promise, parsed.Reason = FindPromise(result.Result)
+	if result.IsError || strings.HasPrefix(result.Subtype, "error") {
+		// The result text of a failed turn is the error message
+		parsed.Type = OutputError
+		if message := snippet(result.Result, 1, 120); message != "" {
+			parts = append(parts, "- "+message)
 		}
-	case "WebSearch":
-		if query, ok := input["query"].(string); ok {
-			return truncate(query, 40)
-		}
-	case "BashOutput":
-		if bashID, ok := input["bash_id"].(string); ok {
-			return truncate(bashID, 40)
-		}
-	case "MultiEdit":
-		if path, ok := input["file_path"].(string); ok {
-			return shortenPath(path)
-		}
-	case "KillShell":
-		if shellID, ok := input["shell_id"].(string); ok {
-			return truncate(shellID, 40)
-		}
-	case "NotebookEdit":
-		if path, ok := input["notebook_path"].(string); ok {
-			return shortenPath(path)
-		}
-	case "TaskOutput":
-		if taskID, ok := input["task_id"].(string); ok {
-			return truncate(taskID, 40)
+		parsed.Failure = Classify(result.Result)
+		if parsed.Failure.Throttled() {
+			parsed.ResetAt = ResetTime(result.Result, time.Now())
 		}
 	}
+	parsed.Display = strings.Join(parts, " ")
 
-	// Generic fallback: try common field names
-	for _, key := range []string{"file_path", "path", "command", "query", "pattern", "description", "skill"} {
-		if val, ok := input[key].(string); ok && val != "" {
-			return truncate(val, 40)
-		}
-	}
-
-	return ""
+	return []ParseResult{parsed}
 }
 
 // shortenPath returns the base filename or last path component
@@ -219,6 +399,46 @@ func truncate(s string, max int) string {
 	return s[:max-3] + "..."
 }
 
+// todoList returns the todo list a TodoWrite call of the main agent sets,
+// or nil for other calls. Sub-agents keep their own lists.
+func todoList(block ContentBlock, parentID string) []Todo {
+	if block.Name != "TodoWrite" || parentID != "" {
+		return nil
+	}
+	todos := parseTodos(block.Input)
+	if todos == nil {
+		todos = []Todo{}
+	}
+	return todos
+}
+
+// parseTodos decodes the todos of a TodoWrite input
+func parseTodos(input map[string]any) []Todo {
+	data, err := json.Marshal(input["todos"])
+	if err != nil {
+		return nil
+	}
+	var todos []Todo
+	if err := json.Unmarshal(data, &todos); err != nil {
+		return nil
+	}
+	return todos
+}
+
+// snippet returns the first non-empty lines of text, each truncated
+func snippet(text string, lines, width int) string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if len(out) == lines {
+			break
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, truncate(line, width))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
 // capitalize capitalizes the first letter of a string
 func capitalize(s string) string {
 	if s == "" {
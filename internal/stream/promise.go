@@ -0,0 +1,29 @@
+package stream
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Promise is a stop signal the agent sends in a <promise> tag, optionally
+// followed by a reason: <promise>BLOCKED: needs an API key</promise>
+type Promise string
+
+const (
+	PromiseNone       Promise = ""
+	PromiseComplete   Promise = "COMPLETE"    // All stories pass
+	PromiseBlocked    Promise = "BLOCKED"     // The current story can't be done
+	PromiseNeedsHuman Promise = "NEEDS_HUMAN" // Stop the run until someone steps in
+)
+
+var promisePattern = regexp.MustCompile(`(?s)<promise>\s*([A-Za-z_]+)\s*(?::\s*(.*?))?\s*</promise>`)
+
+// FindPromise returns the last promise tag in text and its reason
+func FindPromise(text string) (Promise, string) {
+	matches := promisePattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return PromiseNone, ""
+	}
+	last := matches[len(matches)-1]
+	return Promise(strings.ToUpper(last[1])), strings.TrimSpace(last[2])
+}
@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxLineBytes is the longest stream-json line kept in memory. Events with
+// large tool results or file contents can be several megabytes; anything
+// beyond this is skipped rather than buffered.
+const MaxLineBytes = 64 << 20
+
+// LineTooLongError reports a line that was skipped for exceeding the limit
+type LineTooLongError struct {
+	Size  int // Length of the skipped line in bytes
+	Limit int
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("skipped a %d byte stream line (limit %d bytes)", e.Size, e.Limit)
+}
+
+// LineReader reads newline-delimited stream-json. Unlike bufio.Scanner it
+// doesn't stop at long lines: they are read in full, or skipped with a
+// LineTooLongError when over the limit, and reading continues after them.
+type LineReader struct {
+	r   *bufio.Reader
+	max int
+}
+
+// NewLineReader creates a line reader for r
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{r: bufio.NewReaderSize(r, 64*1024), max: MaxLineBytes}
+}
+
+// ReadLine returns the next line without its line ending. It returns io.EOF
+// once the input is exhausted; a final line without a newline is still
+// returned first.
+func (lr *LineReader) ReadLine() (string, error) {
+	var line []byte
+	size := 0
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		size += len(chunk)
+		if size <= lr.max {
+			line = append(line, chunk...)
+		} else {
+			line = nil
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && (err != io.EOF || size == 0) {
+			return "", err
+		}
+
+		if size > lr.max {
+			return "", &LineTooLongError{Size: size, Limit: lr.max}
+		}
+		return string(trimLineEnding(line)), nil
+	}
+}
+
+// trimLineEnding removes a trailing "\n" or "\r\n"
+func trimLineEnding(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+	}
+	return line
+}
@@ -0,0 +1,265 @@
+package stream
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineReader(t *testing.T) {
+	long := `{"type":"user","content":"` + strings.Repeat("x", 200*1024) + `"}`
+	input := long + "\n" + strings.Repeat("y", 2000) + "\r\nlast"
+
+	lr := NewLineReader(strings.NewReader(input))
+
+	// Longer than bufio.Scanner's default limit, but within ours
+	lr.max = 1 << 20
+	line, err := lr.ReadLine()
+	if err != nil || line != long {
+		t.Fatalf("long line: got %d bytes, %v", len(line), err)
+	}
+
+	// Over the limit: skipped, and reading continues after it
+	lr.max = 1000
+	_, err = lr.ReadLine()
+	var tooLong *LineTooLongError
+	if !errors.As(err, &tooLong) || tooLong.Size != 2002 {
+		t.Fatalf("oversized line: err = %v", err)
+	}
+
+	if line, err := lr.ReadLine(); err != nil || line != "last" {
+		t.Fatalf("last line = %q, %v", line, err)
+	}
+	if _, err := lr.ReadLine(); err != io.EOF {
+		t.Fatalf("err = %v, want io.EOF", err)
+	}
+}
+
+func TestParseLineUndecodable(t *testing.T) {
+	p := NewParser()
+
+	if r := p.ParseLine(`{"type": 42}`); len(r) != 1 || r[0].Type != OutputWarning {
+		t.Errorf("undecodable event = %+v, want a warning", r)
+	}
+	if r := p.ParseLine("plain text"); len(r) != 1 || r[0].Type != OutputText || r[0].Display != "plain text" {
+		t.Errorf("plain text = %+v", r)
+	}
+}
+
+func TestParseLineAllBlocks(t *testing.T) {
+	line := `{"type":"assistant","message":{"content":[` +
+		`{"type":"text","text":"Reading both files"},` +
+		`{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/src/a.go"}},` +
+		`{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/src/b.go"}}]}}`
+
+	results := NewParser().ParseLine(line)
+	want := []struct {
+		typ     OutputType
+		display string
+	}{
+		{OutputText, "Reading both files"},
+		{OutputToolCall, "a.go"},
+		{OutputToolCall, "b.go"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		if results[i].Type != w.typ || results[i].Display != w.display {
+			t.Errorf("result %d = %+v, want %v %q", i, results[i], w.typ, w.display)
+		}
+	}
+
+	if results := NewParser().ParseLine(`{"type":"user","message":{}}`); len(results) != 0 {
+		t.Errorf("user event = %+v, want nothing", results)
+	}
+}
+
+func TestParsePartialMessages(t *testing.T) {
+	p := NewParser()
+	var results []ParseResult
+	for _, line := range []string{
+		`{"type":"stream_event","event":{"type":"message_start","message":{"id":"msg_1"}}}`,
+		`{"type":"stream_event","event":{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}}`,
+		`{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}}`,
+		`{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check"}}}`,
+		`{"type":"stream_event","event":{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"t1","name":"Read","input":{}}}}`,
+		`{"type":"stream_event","event":{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"file_path\": \"/src/ma"}}}`,
+		// The complete message follows, one block per event
+		`{"type":"assistant","message":{"id":"msg_1","content":[{"type":"text","text":"Let me check"}]}}`,
+		`{"type":"assistant","message":{"id":"msg_1","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/src/main.go"}}]}}`,
+	} {
+		results = append(results, p.ParseLine(line)...)
+	}
+
+	want := []struct {
+		display string
+		partial bool
+		block   string
+	}{
+		{"Let me ", true, "msg_1/0"},
+		{"Let me check", true, "msg_1/0"},
+		{"", true, "msg_1/1"},
+		{"ma", true, "msg_1/1"},
+		{"Let me check", false, "msg_1/0"},
+		{"main.go", false, "msg_1/1"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Display != w.display || r.Partial != w.partial || r.BlockID != w.block {
+			t.Errorf("result %d = %q partial=%v block=%q, want %q partial=%v block=%q",
+				i, r.Display, r.Partial, r.BlockID, w.display, w.partial, w.block)
+		}
+	}
+}
+
+func TestParseInit(t *testing.T) {
+	results := NewParser().ParseLine(`{"type":"system","subtype":"init","session_id":"s-1","model":"claude-sonnet-4-5",` +
+		`"cwd":"/src","tools":["Read","Bash"],"mcp_servers":[{"name":"github","status":"connected"}]}`)
+	if len(results) != 1 || results[0].Session == nil {
+		t.Fatalf("results = %+v, want one with a session", results)
+	}
+	s := results[0].Session
+	if s.ID != "s-1" || s.Model != "claude-sonnet-4-5" || s.CWD != "/src" || len(s.Tools) != 2 {
+		t.Errorf("session = %+v", s)
+	}
+	if want := "claude-sonnet-4-5 · 2 tools · MCP 1/1"; s.Summary() != want {
+		t.Errorf("Summary() = %q, want %q", s.Summary(), want)
+	}
+}
+
+func TestParseThrottledResult(t *testing.T) {
+	results := NewParser().ParseLine(`{"type":"result","subtype":"success","is_error":true,` +
+		`"result":"Claude AI usage limit reached|1760000000","session_id":"s-1"}`)
+	if len(results) != 1 {
+		t.Fatalf("results = %+v, want one", results)
+	}
+	r := results[0]
+	if r.Type != OutputError || r.Failure != FailureUsageLimit {
+		t.Errorf("type = %v failure = %v, want error and usage limit", r.Type, r.Failure)
+	}
+	if !r.ResetAt.Equal(time.Unix(1760000000, 0)) {
+		t.Errorf("ResetAt = %v, want the announced epoch", r.ResetAt)
+	}
+	if !strings.Contains(r.Display, "usage limit reached") {
+		t.Errorf("Display = %q, want the error message", r.Display)
+	}
+}
+
+func TestClassifyAndResetTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 16, 0, 0, 0, time.Local)
+	tests := []struct {
+		text    string
+		failure Failure
+		reset   time.Time
+	}{
+		{`API Error: 529 {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, FailureOverloaded, time.Time{}},
+		{`API Error: 429 {"type":"error","error":{"type":"rate_limit_error","message":"Too many requests, retry after 30 seconds"}}`, FailureRateLimit, now.Add(30 * time.Second)},
+		{"5-hour limit reached ∙ resets 3pm", FailureUsageLimit, time.Date(2025, 6, 2, 15, 0, 0, 0, time.Local)},
+		{"Claude AI usage limit reached, resets at 17:30", FailureUsageLimit, time.Date(2025, 6, 1, 17, 30, 0, 0, time.Local)},
+		{"Invalid API key · Please run /login", FailureAuth, time.Time{}},
+		{"/bin/sh: claude: command not found", FailureCommandNotFound, time.Time{}},
+		{"Error: ENOENT: no such file or directory, open 'src/missing.ts'", FailureNone, time.Time{}},
+		{"socket hang up", FailureNone, time.Time{}},
+		// Tool output that only mentions a limit or an auth failure
+		{"FAIL src/auth.test.ts: expected 200, got 401 Unauthorized", FailureNone, time.Time{}},
+		{"warn: rate limit of 100 requests per minute exceeded by the load test", FailureNone, time.Time{}},
+		{"upstream overloaded, retrying request 2/3", FailureNone, time.Time{}},
+		{"the 24-hour limit is enforced by quota.go", FailureNone, time.Time{}},
+		{"usage limit checks passed", FailureNone, time.Time{}},
+		{"/bin/sh: jest: command not found", FailureNone, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.text); got != tt.failure {
+			t.Errorf("Classify(%q) = %v, want %v", tt.text, got, tt.failure)
+		}
+		if got := ResetTime(tt.text, now); !got.Equal(tt.reset) {
+			t.Errorf("ResetTime(%q) = %v, want %v", tt.text, got, tt.reset)
+		}
+	}
+}
+
+func TestResetTimeZone(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	// 16:00 in Paris (CEST) is 14:00 UTC
+	now := time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text  string
+		reset time.Time
+	}{
+		{"5-hour limit reached ∙ resets 3pm (Europe/Paris)", time.Date(2025, 6, 2, 15, 0, 0, 0, paris)},
+		{"5-hour limit reached ∙ resets 5pm (Europe/Paris)", time.Date(2025, 6, 1, 17, 0, 0, 0, paris)},
+		{"Usage limit reached, resets at 15:30 UTC", time.Date(2025, 6, 1, 15, 30, 0, 0, time.UTC)},
+		{"Usage limit reached, resets at 13:30 GMT", time.Date(2025, 6, 2, 13, 30, 0, 0, time.UTC)},
+		{"5-hour limit reached ∙ resets 3pm (Nowhere/Unknown)", time.Date(2025, 6, 1, 15, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := ResetTime(tt.text, now); !got.Equal(tt.reset) {
+			t.Errorf("ResetTime(%q) = %v, want %v", tt.text, got, tt.reset)
+		}
+	}
+}
+
+func TestParsePromise(t *testing.T) {
+	p := NewParser()
+	results := p.ParseLine(`{"type":"assistant","message":{"content":[{"type":"text",` +
+		`"text":"Can't do this one.\n<promise>BLOCKED: needs a Stripe API key</promise>"}]}}`)
+	if len(results) != 1 || results[0].Promise != PromiseBlocked || results[0].Reason != "needs a Stripe API key" {
+		t.Errorf("assistant text = %+v, want BLOCKED with reason", results)
+	}
+
+	results = p.ParseLine(`{"type":"result","subtype":"success","result":"All done.\n<promise>COMPLETE</promise>"}`)
+	if len(results) != 1 || results[0].Promise != PromiseComplete || results[0].Reason != "" {
+		t.Errorf("result = %+v, want COMPLETE without reason", results)
+	}
+
+	// A sub-agent can't end the iteration
+	results = p.ParseLine(`{"type":"assistant","parent_tool_use_id":"task1","message":{"content":[{"type":"text","text":"<promise>COMPLETE</promise>"}]}}`)
+	if len(results) != 1 || results[0].Promise != PromiseNone || results[0].ParentID != "task1" {
+		t.Errorf("sub-agent text = %+v, want no promise under task1", results)
+	}
+
+	if promise, _ := FindPromise("I'll reply with a promise tag when done"); promise != PromiseNone {
+		t.Errorf("FindPromise without tag = %q, want none", promise)
+	}
+	if promise, reason := FindPromise("<promise>needs_human:\n  who owns this?</promise>"); promise != PromiseNeedsHuman || reason != "who owns this?" {
+		t.Errorf("FindPromise = %q %q, want NEEDS_HUMAN with reason", promise, reason)
+	}
+}
+
+func TestToolRules(t *testing.T) {
+	p := NewParser(
+		ToolRule{Match: "mcp__linear__*", Fields: []string{"team", "title"}, Max: 20, Icon: "◆"},
+		ToolRule{Match: "Bash", Fields: []string{"description"}, Color: "208"},
+	)
+	tests := []struct {
+		name    string
+		input   string
+		context string
+		style   ToolStyle
+	}{
+		{"Read", `{"file_path":"/src/internal/main.go"}`, "main.go", ToolStyle{}},
+		{"Bash", `{"command":"go test ./...","description":"Run tests"}`, "Run tests", ToolStyle{Color: "208"}},
+		{"mcp__github__get_issue", `{"owner":"kento","repo":"ralph","id":42}`, "42", ToolStyle{Label: "github/get_issue", Color: "#2DD4BF"}},
+		{"mcp__linear__create_issue", `{"team":"CORE","title":"Fix the flaky parser test"}`, "CORE Fix the flak...", ToolStyle{Label: "linear/create_issue", Icon: "◆"}},
+		{"Unknown", `{"options":{"deep":true}}`, "", ToolStyle{}},
+	}
+	for _, tt := range tests {
+		line := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"` + tt.name + `","input":` + tt.input + `}]}}`
+		results := p.ParseLine(line)
+		if len(results) != 1 {
+			t.Fatalf("%s: results = %+v", tt.name, results)
+		}
+		if r := results[0]; r.Context != tt.context || r.ToolStyle != tt.style {
+			t.Errorf("%s: context %q style %+v, want %q %+v", tt.name, r.Context, r.ToolStyle, tt.context, tt.style)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package stream
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ToolRule tells how calls of the tools it matches are shown: which input
+// fields make up the context next to the tool name, and the name's label,
+// color and icon
+type ToolRule struct {
+	Match    string   // Tool name or glob, e.g. "mcp__github__*"
+	Fields   []string // Input fields shown, joined; none picks a common one
+	Max      int      // Length the context is truncated to (default 40)
+	Basename bool     // Show only the last element of paths
+	Label    string   // Shown instead of the tool name
+	Color    string   // Name color, hex ("#38BDF8") or ANSI ("39")
+	Icon     string   // Shown before the name
+}
+
+// DefaultToolRules are the display rules of Claude's built-in tools and of
+// MCP tools. Rules from the config go before them, so they can override.
+var DefaultToolRules = []ToolRule{
+	{Match: "Read", Fields: []string{"file_path"}, Basename: true},
+	{Match: "Write", Fields: []string{"file_path"}, Basename: true},
+	{Match: "Edit", Fields: []string{"file_path"}, Basename: true},
+	{Match: "MultiEdit", Fields: []string{"file_path"}, Basename: true},
+	{Match: "NotebookEdit", Fields: []string{"notebook_path"}, Basename: true},
+	{Match: "Bash", Fields: []string{"command"}, Max: 50},
+	{Match: "Glob", Fields: []string{"pattern"}},
+	{Match: "Grep", Fields: []string{"pattern"}},
+	{Match: "Task", Fields: []string{"description"}},
+	{Match: "Skill", Fields: []string{"skill"}},
+	{Match: "WebFetch", Fields: []string{"url"}, Max: 50},
+	{Match: "WebSearch", Fields: []string{"query"}},
+	{Match: "BashOutput", Fields: []string{"bash_id"}},
+	{Match: "KillShell", Fields: []string{"shell_id"}},
+	{Match: "TaskOutput", Fields: []string{"task_id"}},
+	{Match: "mcp__*", Color: "#2DD4BF"},
+}
+
+// commonFields are the input fields tried, in order, for tools without a
+// rule or whose rule lists none
+var commonFields = []string{"file_path", "path", "command", "query", "pattern", "url", "description", "skill", "title", "name", "id"}
+
+// defaultContextMax is the context length of rules without Max
+const defaultContextMax = 40
+
+// ToolStyle is how a tool's name is shown, from its display rule
+type ToolStyle struct {
+	Label string // Empty to show the tool name
+	Color string
+	Icon  string
+}
+
+// rule returns the first of the parser's rules matching a tool, or nil
+func (p *Parser) rule(name string) *ToolRule {
+	for _, rules := range [][]ToolRule{p.rules, DefaultToolRules} {
+		for i, r := range rules {
+			if matched, err := path.Match(r.Match, name); err == nil && matched {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// toolStyle returns the label, color and icon of a tool. MCP tools are
+// labeled server/tool unless their rule sets a label.
+func (p *Parser) toolStyle(name string) ToolStyle {
+	var style ToolStyle
+	if r := p.rule(name); r != nil {
+		style = ToolStyle{Label: r.Label, Color: r.Color, Icon: r.Icon}
+	}
+	if rest, ok := strings.CutPrefix(name, "mcp__"); ok && style.Label == "" {
+		if server, tool, ok := strings.Cut(rest, "__"); ok {
+			style.Label = server + "/" + tool
+		}
+	}
+	return style
+}
+
+// toolContext extracts a brief context string from tool input, as the
+// tool's display rule says
+func (p *Parser) toolContext(name string, input map[string]any) string {
+	if input == nil {
+		return ""
+	}
+	if name == "TodoWrite" {
+		todos := parseTodos(input)
+		done := 0
+		for _, todo := range todos {
+			if todo.Status == TodoCompleted {
+				done++
+			}
+		}
+		return fmt.Sprintf("%d/%d done", done, len(todos))
+	}
+
+	rule := p.rule(name)
+	if rule == nil {
+		rule = &ToolRule{}
+	}
+	limit := rule.Max
+	if limit <= 0 {
+		limit = defaultContextMax
+	}
+
+	// Without fields, the first common field there is
+	if len(rule.Fields) == 0 {
+		for _, key := range commonFields {
+			if value := fieldValue(input, key, rule.Basename); value != "" {
+				return truncate(value, limit)
+			}
+		}
+		return ""
+	}
+
+	var values []string
+	for _, key := range rule.Fields {
+		if value := fieldValue(input, key, rule.Basename); value != "" {
+			values = append(values, value)
+		}
+	}
+	return truncate(strings.Join(values, " "), limit)
+}
+
+// fieldValue renders a string, number or boolean input field, or returns
+// "" for other and missing fields
+func fieldValue(input map[string]any, key string, basename bool) string {
+	switch value := input[key].(type) {
+	case string:
+		if basename && value != "" {
+			return shortenPath(value)
+		}
+		return value
+	case float64, bool:
+		return fmt.Sprint(value)
+	default:
+		return ""
+	}
+}
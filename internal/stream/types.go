@@ -1,5 +1,11 @@
 package stream
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 // StreamEvent is the base type for determining event type
 type StreamEvent struct {
 	Type    string `json:"type"`
@@ -8,25 +14,68 @@ type StreamEvent struct {
 
 // SystemEvent represents system messages (init, hook_response, etc.)
 type SystemEvent struct {
-	Type      string `json:"type"`
-	Subtype   string `json:"subtype,omitempty"`
-	SessionID string `json:"session_id,omitempty"`
+	Type       string      `json:"type"`
+	Subtype    string      `json:"subtype,omitempty"`
+	SessionID  string      `json:"session_id,omitempty"`
+	Model      string      `json:"model,omitempty"`
+	CWD        string      `json:"cwd,omitempty"`
+	Tools      []string    `json:"tools,omitempty"`
+	MCPServers []MCPServer `json:"mcp_servers,omitempty"`
+}
+
+// MCPServer is an MCP server listed in the init event
+type MCPServer struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Session describes the agent session of an iteration, from its init event
+type Session struct {
+	ID         string      `json:"id"`
+	Model      string      `json:"model,omitempty"`
+	CWD        string      `json:"cwd,omitempty"`
+	Tools      []string    `json:"tools,omitempty"`
+	MCPServers []MCPServer `json:"mcpServers,omitempty"`
 }
 
-// AssistantEvent represents assistant messages with nested content
+// Summary renders the session on one line: model, tools and MCP servers
+func (s *Session) Summary() string {
+	var parts []string
+	if s.Model != "" {
+		parts = append(parts, s.Model)
+	}
+	parts = append(parts, fmt.Sprintf("%d tools", len(s.Tools)))
+	if len(s.MCPServers) > 0 {
+		connected := 0
+		for _, server := range s.MCPServers {
+			if server.Status == "connected" {
+				connected++
+			}
+		}
+		parts = append(parts, fmt.Sprintf("MCP %d/%d", connected, len(s.MCPServers)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// AssistantEvent represents assistant messages with nested content.
+// Messages of a sub-agent carry the tool_use ID of the Task call that
+// started it.
 type AssistantEvent struct {
-	Type    string  `json:"type"`
-	Message Message `json:"message"`
+	Type            string  `json:"type"`
+	Message         Message `json:"message"`
+	ParentToolUseID string  `json:"parent_tool_use_id,omitempty"`
 }
 
 // UserEvent represents user messages (including tool results)
 type UserEvent struct {
-	Type    string  `json:"type"`
-	Message Message `json:"message"`
+	Type            string  `json:"type"`
+	Message         Message `json:"message"`
+	ParentToolUseID string  `json:"parent_tool_use_id,omitempty"`
 }
 
 // Message holds the actual message content
 type Message struct {
+	ID      string         `json:"id,omitempty"`
 	Role    string         `json:"role,omitempty"`
 	Content []ContentBlock `json:"content,omitempty"`
 }
@@ -39,7 +88,76 @@ type ContentBlock struct {
 	Name      string                 `json:"name,omitempty"`
 	Input     map[string]interface{} `json:"input,omitempty"`
 	ToolUseID string                 `json:"tool_use_id,omitempty"`
-	Content   string                 `json:"content,omitempty"`
+	Content   ResultContent          `json:"content,omitempty"`
+	IsError   bool                   `json:"is_error,omitempty"`
+}
+
+// Todo is an item of the agent's todo list, from the input of TodoWrite
+type Todo struct {
+	Content    string `json:"content"`
+	Status     string `json:"status"` // pending, in_progress or completed
+	ActiveForm string `json:"activeForm,omitempty"`
+}
+
+// Todo statuses
+const (
+	TodoPending    = "pending"
+	TodoInProgress = "in_progress"
+	TodoCompleted  = "completed"
+)
+
+// ResultContent is the text of a tool_result, sent either as a string or
+// as a list of content blocks
+type ResultContent string
+
+// UnmarshalJSON accepts both forms, joining the text of the blocks
+func (c *ResultContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = ResultContent(text)
+		return nil
+	}
+
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	*c = ResultContent(strings.Join(parts, "\n"))
+	return nil
+}
+
+// PartialEvent wraps an API streaming event, sent while a message is being
+// generated when the agent runs with --include-partial-messages
+type PartialEvent struct {
+	Type            string   `json:"type"`
+	Event           APIEvent `json:"event"`
+	ParentToolUseID string   `json:"parent_tool_use_id,omitempty"`
+}
+
+// APIEvent is a message_start, content_block_start, content_block_delta,
+// content_block_stop or message_stop event
+type APIEvent struct {
+	Type         string       `json:"type"`
+	Index        int          `json:"index"`
+	Message      Message      `json:"message"`       // message_start
+	ContentBlock ContentBlock `json:"content_block"` // content_block_start
+	Delta        Delta        `json:"delta"`         // content_block_delta
+}
+
+// Delta is an increment of a content block: text_delta or input_json_delta
+type Delta struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
 }
 
 // ResultEvent represents the final completion event
@@ -8,27 +8,79 @@ import (
 	"github.com/kento/ralph/internal/ui/styles"
 )
 
+// Tool is a tool call as shown in the transcript. Label, Color and Icon
+// come from the tool's display rule and are optional.
+type Tool struct {
+	Name    string
+	Context string
+	Label   string // Shown instead of Name
+	Color   string // Name color, hex or ANSI
+	Icon    string // Shown before the name
+}
+
 // FormatToolCall formats a tool invocation with pending icon
-func FormatToolCall(name, context string) string {
-	icon := styles.Muted.Render(styles.ToolPending)
+func FormatToolCall(tool Tool) string {
+	return formatTool(styles.Muted.Render(styles.ToolPending), tool)
+}
+
+// FormatToolResult formats a finished tool invocation with a success or
+// error icon
+func FormatToolResult(tool Tool, failed bool) string {
+	icon := styles.SuccessText.Render(styles.CheckIcon)
+	if failed {
+		icon = styles.ErrorText.Render(styles.ErrorIcon)
+	}
+	return formatTool(icon, tool)
+}
 
-	// Use distinct color for Task (sub-agent)
-	var color lipgloss.Color
-	if name == "Task" {
+// FormatToolOutput formats the output of a failed tool call, indented
+// under the call
+func FormatToolOutput(output string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = "  " + styles.Subtle.Render(styles.ToolOutput) + " " + styles.ErrorText.Render(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatTool(status string, tool Tool) string {
+	// Use distinct color for Task (sub-agent) unless a rule sets one
+	color := lipgloss.Color(tool.Color)
+	if tool.Color == "" && tool.Name == "Task" {
 		color = styles.Agent
-	} else {
+	} else if tool.Color == "" {
 		color = styles.Secondary
 	}
 
+	name := tool.Name
+	if tool.Label != "" {
+		name = tool.Label
+	}
+	if tool.Icon != "" {
+		name = tool.Icon + " " + name
+	}
 	toolName := lipgloss.NewStyle().
 		Foreground(color).
 		Bold(true).
 		Render(name)
 
-	if context != "" {
-		return fmt.Sprintf("%s %s %s", icon, toolName, styles.Muted.Render(context))
+	if tool.Context != "" {
+		return fmt.Sprintf("%s %s %s", status, toolName, styles.Muted.Render(tool.Context))
+	}
+	return fmt.Sprintf("%s %s", status, toolName)
+}
+
+// FormatTodo formats an item of the agent's todo list by status:
+// completed, in_progress or pending
+func FormatTodo(text, status string) string {
+	switch status {
+	case "completed":
+		return styles.SuccessText.Render(styles.CheckIcon) + " " + styles.Muted.Render(text)
+	case "in_progress":
+		return styles.Title.Render(styles.TodoActive + " " + text)
+	default:
+		return styles.Muted.Render(styles.TodoPending) + " " + text
 	}
-	return fmt.Sprintf("%s %s", icon, toolName)
 }
 
 // FormatError formats an error message with a prominent label
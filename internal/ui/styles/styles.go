@@ -58,6 +58,9 @@ const (
 	ErrorIcon   = "×"
 	WarningIcon = "⚠"
 	ToolPending = "●"
+	ToolOutput  = "⎿"
+	TodoPending = "○"
+	TodoActive  = "◐"
 	Arrow       = "→"
 	Bullet      = "•"
 )